@@ -3,11 +3,15 @@ package diff
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
+	"github.com/arutselvan15/go-utils/redact"
 	r3 "github.com/r3labs/diff"
 )
 
-//GetDiffChangelog log
+//GetDiffChangelog log, the values of the fields tagged `redact:"true"` are masked, the changed values having
+// such fields (a struct set from nil for instance) are the json with the tagged fields masked
 func GetDiffChangelog(oldObj, newObj interface{}) (*r3.Changelog, error) {
 	if r3.Changed(oldObj, newObj) {
 		changelog, err := r3.Diff(oldObj, newObj)
@@ -15,12 +19,98 @@ func GetDiffChangelog(oldObj, newObj interface{}) (*r3.Changelog, error) {
 			return nil, err
 		}
 
+		t := reflect.TypeOf(oldObj)
+		if t == nil {
+			t = reflect.TypeOf(newObj)
+		}
+
+		for i, c := range changelog {
+			if redactedPath(t, c.Path) {
+				if c.From != nil {
+					changelog[i].From = redact.Mask
+				}
+
+				if c.To != nil {
+					changelog[i].To = redact.Mask
+				}
+
+				continue
+			}
+
+			changelog[i].From = maskTaggedValue(c.From)
+			changelog[i].To = maskTaggedValue(c.To)
+		}
+
 		return &changelog, nil
 	}
 
 	return nil, nil
 }
 
+// redactedPath checks the change path goes through a tagged field, the struct fields are named like r3 does
+func redactedPath(t reflect.Type, path []string) bool {
+	for _, name := range path {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil {
+			return false
+		}
+
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			field, ok := diffField(t, name)
+			if !ok {
+				return false
+			}
+
+			if redact.Tagged(field) {
+				return true
+			}
+
+			t = field.Type
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// maskTaggedValue returns the json of the value with the tagged fields masked, the value when it has none
+func maskTaggedValue(value interface{}) interface{} {
+	if value == nil || !redact.HasTagged(reflect.TypeOf(value)) {
+		return value
+	}
+
+	b, err := redact.Marshal(value)
+	if err != nil {
+		return redact.Mask
+	}
+
+	return string(b)
+}
+
+func diffField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tname := strings.Split(field.Tag.Get("diff"), ",")[0]
+		if tname == "" {
+			tname = field.Name
+		}
+
+		if tname == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
 //GetDiffString log
 func GetDiffString(oldObj, newObj interface{}) ([]string, error) {
 	var results []string
@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"strings"
 	"testing"
 
 	r3 "github.com/r3labs/diff"
@@ -82,3 +83,73 @@ func TestGetDiffString(t *testing.T) {
 		})
 	}
 }
+
+type Credentials struct {
+	User     string
+	Password string `redact:"true"`
+}
+
+type SecretObj struct {
+	Name        string
+	Credentials *Credentials
+	Keys        []Credentials `diff:"keys"`
+}
+
+func TestGetDiffChangelogRedact(t *testing.T) {
+	oldObj := SecretObj{Name: "app1", Credentials: &Credentials{User: "u1", Password: "p1"}, Keys: []Credentials{{Password: "k1"}}}
+	newObj := SecretObj{Name: "app2", Credentials: &Credentials{User: "u2", Password: "p2"}, Keys: []Credentials{{Password: "k2"}}}
+
+	changelog, err := GetDiffChangelog(oldObj, newObj)
+	if err != nil {
+		t.Fatalf("GetDiffChangelog() error = %v", err)
+	}
+
+	want := map[string][2]interface{}{
+		"Name":                 {"app1", "app2"},
+		"Credentials.User":     {"u1", "u2"},
+		"Credentials.Password": {"[REDACTED]", "[REDACTED]"},
+		"keys.0.Password":      {"[REDACTED]", "[REDACTED]"},
+	}
+
+	if len(*changelog) != len(want) {
+		t.Fatalf("GetDiffChangelog() = %v, want %d changes", *changelog, len(want))
+	}
+
+	for _, c := range *changelog {
+		path := strings.Join(c.Path, ".")
+		if w, ok := want[path]; !ok || w[0] != c.From || w[1] != c.To {
+			t.Errorf("GetDiffChangelog() change %s from %v to %v, want %v", path, c.From, c.To, w)
+		}
+	}
+}
+
+func TestGetDiffChangelogRedactSetFromNil(t *testing.T) {
+	oldObj := SecretObj{Name: "app1"}
+	newObj := SecretObj{Name: "app1", Credentials: &Credentials{User: "u", Password: "hunter2"}}
+
+	changelog, err := GetDiffChangelog(oldObj, newObj)
+	if err != nil {
+		t.Fatalf("GetDiffChangelog() error = %v", err)
+	}
+
+	if len(*changelog) != 1 {
+		t.Fatalf("GetDiffChangelog() = %v, want 1 change", *changelog)
+	}
+
+	c := (*changelog)[0]
+	if want := `{"Password":"[REDACTED]","User":"u"}`; c.From != nil || c.To != want {
+		t.Errorf("GetDiffChangelog() change %v from %v to %v, want to %v", c.Path, c.From, c.To, want)
+	}
+
+	// and back to nil
+	changes, err := GetDiffString(newObj, oldObj)
+	if err != nil {
+		t.Fatalf("GetDiffString() error = %v", err)
+	}
+
+	for _, change := range changes {
+		if strings.Contains(change, "hunter2") {
+			t.Errorf("GetDiffString() = %v, the password is not masked", changes)
+		}
+	}
+}
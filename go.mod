@@ -1,13 +1,18 @@
 module github.com/arutselvan15/go-utils
 
 require (
-	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/go-logr/logr v1.2.4
 	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
 	github.com/mattn/go-colorable v0.1.2
 	github.com/r3labs/diff v0.0.0-20190801153147-a71de73c46ad
+	github.com/rs/zerolog v1.26.1
 	github.com/sirupsen/logrus v1.4.2
-	github.com/snowzach/rotatefilehook v0.0.0-20180327172521-2f64f265f58c
-	github.com/stretchr/testify v1.2.2
-	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
-	gopkg.in/yaml.v2 v2.2.2
+	github.com/stretchr/testify v1.7.1
+	go.opentelemetry.io/otel/trace v1.7.0
+	go.uber.org/zap v1.21.0
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.25.0
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0
+	gopkg.in/yaml.v2 v2.2.8
+	k8s.io/klog/v2 v2.100.1
 )
@@ -0,0 +1,296 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAsyncBufferSize number of entries buffered when none is configured
+const DefaultAsyncBufferSize = 1024
+
+// AsyncPolicy what to do with a new entry when the async buffer is full
+type AsyncPolicy string
+
+var (
+	// BlockWhenFull waits for room in the buffer
+	BlockWhenFull AsyncPolicy = "block"
+	// DropNewest drops the new entry
+	DropNewest AsyncPolicy = "drop-newest"
+	// DropOldest drops the oldest buffered entry
+	DropOldest AsyncPolicy = "drop-oldest"
+	// DropBelowLevel drops the new entry when it is less severe than the drop level, waits otherwise
+	DropBelowLevel AsyncPolicy = "drop-below-level"
+)
+
+// AsyncConfig asynchronous output configuration
+type AsyncConfig struct {
+	// BufferSize number of entries buffered, DefaultAsyncBufferSize when 0
+	BufferSize int `json:"bufferSize,omitempty" yaml:"bufferSize,omitempty"`
+	// Policy when the buffer is full, BlockWhenFull when empty
+	Policy AsyncPolicy `json:"policy,omitempty" yaml:"policy,omitempty"`
+	// DropLevel least severe level kept by DropBelowLevel, warn when empty
+	DropLevel LevelLog `json:"dropLevel,omitempty" yaml:"dropLevel,omitempty"`
+}
+
+// AsyncStats state of the async buffer
+type AsyncStats struct {
+	// Capacity size of the buffer, 0 when the logger is synchronous
+	Capacity int
+	// Buffered entries waiting to be written
+	Buffered int
+	// Dropped entries dropped because the buffer was full
+	Dropped uint64
+	// DroppedByLevel dropped entries per level
+	DroppedByLevel map[LevelLog]uint64
+}
+
+func (c *AsyncConfig) validate() error {
+	if c.BufferSize < 0 {
+		return fmt.Errorf("invalid async buffer size %d", c.BufferSize)
+	}
+
+	switch c.Policy {
+	case "", BlockWhenFull, DropNewest, DropOldest, DropBelowLevel:
+	default:
+		return fmt.Errorf("invalid async policy %q", c.Policy)
+	}
+
+	if c.DropLevel != "" {
+		if _, err := ParseLevel(string(c.DropLevel)); err != nil {
+			return fmt.Errorf("invalid async drop level: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// asyncWrite formatted entry for one output
+type asyncWrite struct {
+	write func(data []byte) error
+	data  []byte
+}
+
+// asyncRecord outputs of one entry
+type asyncRecord struct {
+	level  logrus.Level
+	writes []asyncWrite
+}
+
+// asyncQueue ring buffer of the formatted entries written by a worker goroutine
+type asyncQueue struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+
+	records   []asyncRecord
+	head      int
+	count     int
+	policy    AsyncPolicy
+	dropLevel logrus.Level
+
+	// pushed and processed (written or dropped from the buffer) count the records to know when a flush is done
+	pushed    uint64
+	processed uint64
+	flushes   []asyncFlush
+
+	dropped        uint64
+	droppedByLevel map[logrus.Level]uint64
+	closed         bool
+	done           chan struct{}
+}
+
+type asyncFlush struct {
+	target uint64
+	done   chan struct{}
+}
+
+func newAsyncQueue(cfg AsyncConfig) *asyncQueue {
+	size := cfg.BufferSize
+	if size == 0 {
+		size = DefaultAsyncBufferSize
+	}
+
+	policy := cfg.Policy
+	if policy == "" {
+		policy = BlockWhenFull
+	}
+
+	dropLevel := logrus.WarnLevel
+	if cfg.DropLevel != "" {
+		dropLevel, _ = toLogrusLevel(cfg.DropLevel)
+	}
+
+	q := &asyncQueue{
+		records:        make([]asyncRecord, size),
+		policy:         policy,
+		dropLevel:      dropLevel,
+		droppedByLevel: map[logrus.Level]uint64{},
+		done:           make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+
+	go q.run()
+
+	return q
+}
+
+// push buffers the record following the policy when the buffer is full,
+// the record is written right away when the queue is closed
+func (q *asyncQueue) push(rec asyncRecord) {
+	q.mu.Lock()
+
+	for !q.closed && q.count == len(q.records) {
+		if q.policy == DropNewest || (q.policy == DropBelowLevel && rec.level > q.dropLevel) {
+			q.drop(rec.level)
+			q.mu.Unlock()
+
+			return
+		}
+
+		if q.policy == DropOldest {
+			q.drop(q.records[q.head].level)
+			q.records[q.head] = asyncRecord{}
+			q.head = (q.head + 1) % len(q.records)
+			q.count--
+			q.processed++
+			q.notifyFlushes()
+
+			break
+		}
+
+		q.notFull.Wait()
+	}
+
+	if q.closed {
+		q.mu.Unlock()
+		writeRecord(rec)
+
+		return
+	}
+
+	q.records[(q.head+q.count)%len(q.records)] = rec
+	q.count++
+	q.pushed++
+	q.notEmpty.Signal()
+	q.mu.Unlock()
+}
+
+func (q *asyncQueue) drop(level logrus.Level) {
+	q.dropped++
+	q.droppedByLevel[level]++
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for {
+		q.mu.Lock()
+
+		for q.count == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+
+		if q.count == 0 {
+			q.mu.Unlock()
+			return
+		}
+
+		rec := q.records[q.head]
+		q.records[q.head] = asyncRecord{}
+		q.head = (q.head + 1) % len(q.records)
+		q.count--
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		writeRecord(rec)
+
+		q.mu.Lock()
+		q.processed++
+		q.notifyFlushes()
+		q.mu.Unlock()
+	}
+}
+
+// flush waits for the records buffered before the call to be written
+func (q *asyncQueue) flush(ctx context.Context) error {
+	q.mu.Lock()
+
+	if q.processed >= q.pushed {
+		q.mu.Unlock()
+		return nil
+	}
+
+	f := asyncFlush{target: q.pushed, done: make(chan struct{})}
+	q.flushes = append(q.flushes, f)
+	q.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *asyncQueue) notifyFlushes() {
+	pending := q.flushes[:0]
+
+	for _, f := range q.flushes {
+		if q.processed >= f.target {
+			close(f.done)
+		} else {
+			pending = append(pending, f)
+		}
+	}
+
+	q.flushes = pending
+}
+
+// close writes the buffered records and stops the worker
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+	q.mu.Unlock()
+
+	<-q.done
+}
+
+func (q *asyncQueue) stats() AsyncStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := AsyncStats{
+		Capacity: len(q.records), Buffered: q.count, Dropped: q.dropped, DroppedByLevel: map[LevelLog]uint64{},
+	}
+
+	for level, n := range q.droppedByLevel {
+		stats.DroppedByLevel[fromLogrusLevel(level)] = n
+	}
+
+	return stats
+}
+
+// writeRecord writes the outputs of the entry, errors are reported on stderr like the logrus write errors
+func writeRecord(rec asyncRecord) {
+	for _, w := range rec.writes {
+		if err := w.write(w.data); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+	}
+}
+
+// writerFunc returns the write function of the writer
+func writerFunc(w io.Writer) func([]byte) error {
+	return func(data []byte) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gateWriter blocks the writes until released, started is closed by the first write
+type gateWriter struct {
+	MemoryWriter
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	// logrus writes the empty output of the pipeline formatter in async mode
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	g.once.Do(func() { close(g.started) })
+	<-g.release
+
+	return g.MemoryWriter.Write(p)
+}
+
+func memoryMessages(m *MemoryWriter) []string {
+	var messages []string
+
+	for _, fields := range jsonLines(m) {
+		messages = append(messages, fields["msg"].(string))
+	}
+
+	return messages
+}
+
+func TestEnableAsync(t *testing.T) {
+	console, memory := &MemoryWriter{}, &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType)
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+	assert.Nil(t, logger.EnableAsync(AsyncConfig{}))
+	defer logger.Close()
+
+	assert.Equal(t, DefaultAsyncBufferSize, logger.GetAsyncStats().Capacity)
+
+	logger.Info("one")
+	logger.Debug("filtered")
+	logger.ThreadLogger().Warn("two")
+
+	assert.Nil(t, logger.Flush(context.Background()))
+	assert.Equal(t, []string{"one", "two"}, memoryMessages(console))
+	assert.Equal(t, []string{"one", "two"}, memoryMessages(memory))
+}
+
+func TestEnableAsyncInvalid(t *testing.T) {
+	logger := newLogger()
+
+	assert.NotNil(t, logger.EnableAsync(AsyncConfig{BufferSize: -1}))
+	assert.NotNil(t, logger.EnableAsync(AsyncConfig{Policy: "drop-all"}))
+	assert.NotNil(t, logger.EnableAsync(AsyncConfig{Policy: DropBelowLevel, DropLevel: "loud"}))
+	assert.Equal(t, 0, logger.GetAsyncStats().Capacity)
+
+	_, err := NewFromConfig(Config{Async: &AsyncConfig{Policy: "drop-all"}})
+	assert.NotNil(t, err)
+}
+
+func TestAsyncPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     AsyncConfig
+		want    []string
+		dropped map[LevelLog]uint64
+	}{
+		{
+			name: "block", cfg: AsyncConfig{BufferSize: 2},
+			want: []string{"1", "2", "3", "4", "5"}, dropped: map[LevelLog]uint64{},
+		},
+		{
+			name: "drop newest", cfg: AsyncConfig{BufferSize: 2, Policy: DropNewest},
+			want: []string{"1", "2", "3"}, dropped: map[LevelLog]uint64{InfoLevel: 1, ErrorLevel: 1},
+		},
+		{
+			name: "drop oldest", cfg: AsyncConfig{BufferSize: 2, Policy: DropOldest},
+			want: []string{"1", "4", "5"}, dropped: map[LevelLog]uint64{InfoLevel: 2},
+		},
+		{
+			name: "drop below level", cfg: AsyncConfig{BufferSize: 2, Policy: DropBelowLevel},
+			want: []string{"1", "2", "3", "5"}, dropped: map[LevelLog]uint64{InfoLevel: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := newGateWriter()
+
+			logger := newLogger()
+			logger.logger.Out = gate
+			logger.SetFormatterType(JSONFormatterType)
+			assert.Nil(t, logger.EnableAsync(tt.cfg))
+			defer logger.Close()
+
+			// 1 is being written, 2 and 3 fill the buffer
+			logger.Info("1")
+			<-gate.started
+			logger.Info("2")
+			logger.Info("3")
+
+			done := make(chan struct{})
+
+			go func() {
+				logger.Info("4")
+				logger.Error("5")
+				close(done)
+			}()
+
+			blocked := false
+
+			select {
+			case <-done:
+			case <-time.After(100 * time.Millisecond):
+				blocked = true
+			}
+
+			assert.Equal(t, tt.cfg.Policy == "" || tt.cfg.Policy == DropBelowLevel, blocked)
+
+			close(gate.release)
+			<-done
+
+			assert.Nil(t, logger.Flush(context.Background()))
+			assert.Equal(t, tt.want, memoryMessages(&gate.MemoryWriter))
+
+			stats := logger.GetAsyncStats()
+			assert.Equal(t, tt.dropped, stats.DroppedByLevel)
+			assert.Equal(t, 0, stats.Buffered)
+		})
+	}
+}
+
+func TestAsyncFlushTimeout(t *testing.T) {
+	gate := newGateWriter()
+
+	logger := newLogger()
+	logger.logger.Out = gate
+	assert.Nil(t, logger.EnableAsync(AsyncConfig{}))
+
+	logger.Info("1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, logger.Flush(ctx))
+
+	close(gate.release)
+	assert.Nil(t, logger.Close())
+	assert.Len(t, gate.Lines(), 1)
+	assert.Equal(t, 0, logger.GetAsyncStats().Capacity)
+
+	// back to synchronous writes
+	logger.Info("2")
+	assert.Len(t, gate.Lines(), 2)
+}
+
+func TestAsyncPanicWrittenRightAway(t *testing.T) {
+	console := &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType)
+	assert.Nil(t, logger.EnableAsync(AsyncConfig{}))
+	defer logger.Close()
+
+	logger.Info("1")
+	assert.Panics(t, func() { logger.Panic("2") })
+	assert.Equal(t, []string{"1", "2"}, memoryMessages(console))
+}
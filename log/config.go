@@ -0,0 +1,286 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix prefix of the environment variables read by Config.LoadEnv
+const EnvPrefix = "GOUTILS_LOG_"
+
+// Config logger configuration
+type Config struct {
+	LevelConfig `yaml:",inline"`
+
+	// File log file, no file logging when the path is empty
+	File FileConfig `json:"file,omitempty" yaml:"file,omitempty"`
+	// Fields static context fields (cluster, app, ...)
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Redact redacts the fields and messages with the rules when set
+	Redact *redact.Config `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// Sampling samples and rate limits the entries when set
+	Sampling *SamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	// Async writes the entries in the background when set
+	Async *AsyncConfig `json:"async,omitempty" yaml:"async,omitempty"`
+	// Hooks additional logrus hooks, can only be set in code
+	Hooks []logrus.Hook `json:"-" yaml:"-"`
+}
+
+// FileConfig log file configuration
+type FileConfig struct {
+	// Path file path, can have %Y %m %d %H %M %S timestamp tokens (e.g. /var/log/app-%Y%m%d.log)
+	// replaced with the start of the rotation period
+	Path   string        `json:"path,omitempty" yaml:"path,omitempty"`
+	Format FormatterType `json:"format,omitempty" yaml:"format,omitempty"`
+	// Level most verbose level written to the file, the level of the logger when empty
+	Level LevelLog `json:"level,omitempty" yaml:"level,omitempty"`
+	// MaxSize megabytes before rotation
+	MaxSize int `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+	// MaxAge days to keep rotated files
+	MaxAge int `json:"maxAge,omitempty" yaml:"maxAge,omitempty"`
+	// MaxBackup number of rotated files to keep
+	MaxBackup int `json:"maxBackup,omitempty" yaml:"maxBackup,omitempty"`
+	// Rotation rotates the file hourly or daily on top of MaxSize
+	Rotation RotationInterval `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	// Compress gzips the rotated files
+	Compress bool `json:"compress,omitempty" yaml:"compress,omitempty"`
+}
+
+// ParseConfig parses a YAML (or JSON) config, unknown keys are reported as errors
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid log config: %v", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadConfigFile loads a YAML (or JSON) config file
+func LoadConfigFile(filename string) (Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// LoadEnv overrides the config with the GOUTILS_LOG_* environment variables set:
+// LEVEL, FORMAT, COMPONENTS (webhook=debug,controller=info), FIELDS (cluster=minikube,app=estore),
+// FILE_PATH, FILE_FORMAT, FILE_LEVEL, FILE_MAX_SIZE, FILE_MAX_AGE, FILE_MAX_BACKUP, FILE_ROTATION and FILE_COMPRESS.
+func (c *Config) LoadEnv() error {
+	if v, ok := lookupEnv("LEVEL"); ok {
+		c.Level = LevelLog(v)
+	}
+
+	if v, ok := lookupEnv("FORMAT"); ok {
+		c.Format = FormatterType(v)
+	}
+
+	if v, ok := lookupEnv("COMPONENTS"); ok {
+		components, err := parseEnvMap("COMPONENTS", v)
+		if err != nil {
+			return err
+		}
+
+		c.Components = map[string]LevelLog{}
+		for component, level := range components {
+			c.Components[component] = LevelLog(level)
+		}
+	}
+
+	if v, ok := lookupEnv("FIELDS"); ok {
+		fields, err := parseEnvMap("FIELDS", v)
+		if err != nil {
+			return err
+		}
+
+		c.Fields = fields
+	}
+
+	if v, ok := lookupEnv("FILE_PATH"); ok {
+		c.File.Path = v
+	}
+
+	if v, ok := lookupEnv("FILE_FORMAT"); ok {
+		c.File.Format = FormatterType(v)
+	}
+
+	if v, ok := lookupEnv("FILE_LEVEL"); ok {
+		c.File.Level = LevelLog(v)
+	}
+
+	if v, ok := lookupEnv("FILE_ROTATION"); ok {
+		c.File.Rotation = RotationInterval(v)
+	}
+
+	if v, ok := lookupEnv("FILE_COMPRESS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %sFILE_COMPRESS %q: not a boolean", EnvPrefix, v)
+		}
+
+		c.File.Compress = b
+	}
+
+	for name, value := range map[string]*int{
+		"FILE_MAX_SIZE": &c.File.MaxSize, "FILE_MAX_AGE": &c.File.MaxAge, "FILE_MAX_BACKUP": &c.File.MaxBackup,
+	} {
+		if v, ok := lookupEnv(name); ok {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s %q: not a number", EnvPrefix, name, v)
+			}
+
+			*value = i
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the config
+func (c *Config) Validate() error {
+	if err := c.LevelConfig.validate(); err != nil {
+		return err
+	}
+
+	if c.File.Format != "" && !isFormatterType(c.File.Format) {
+		return fmt.Errorf("invalid file formatter type %q", c.File.Format)
+	}
+
+	if c.File.Level != "" {
+		if _, err := ParseLevel(string(c.File.Level)); err != nil {
+			return fmt.Errorf("invalid file level: %v", err)
+		}
+	}
+
+	if c.File.MaxSize < 0 || c.File.MaxAge < 0 || c.File.MaxBackup < 0 {
+		return fmt.Errorf("invalid file rotation: maxSize, maxAge and maxBackup can not be negative")
+	}
+
+	if c.File.Rotation != "" && c.File.Rotation != HourlyRotation && c.File.Rotation != DailyRotation {
+		return fmt.Errorf("invalid file rotation %q", c.File.Rotation)
+	}
+
+	if err := validatePattern(c.File.Path); err != nil {
+		return err
+	}
+
+	for key := range c.Fields {
+		if key == "" {
+			return fmt.Errorf("invalid field: empty name")
+		}
+	}
+
+	if c.Redact != nil {
+		if _, err := redact.New(*c.Redact); err != nil {
+			return err
+		}
+	}
+
+	if c.Sampling != nil {
+		if err := c.Sampling.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Async != nil {
+		if err := c.Async.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewFromConfig creates a logger from the config, the config is validated first
+func NewFromConfig(cfg Config) (CommonLog, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	l := newRootLog()
+
+	cfg.LevelConfig.apply(l, true)
+
+	if cfg.File.Path != "" {
+		file := cfg.File
+		if file.Format == "" {
+			file.Format = TextFormatterType
+		}
+
+		if err := l.setLogFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, hook := range cfg.Hooks {
+		l.logger.AddHook(&gatedHook{Hook: hook, pipeline: l.pipeline})
+	}
+
+	for key, value := range cfg.Fields {
+		l.setContextField(key, value)
+	}
+
+	if cfg.Redact != nil {
+		r, err := redact.New(*cfg.Redact)
+		if err != nil {
+			return nil, err
+		}
+
+		l.SetRedactor(r)
+	}
+
+	if cfg.Sampling != nil {
+		if err := l.EnableSampling(*cfg.Sampling); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Async != nil {
+		if err := l.EnableAsync(*cfg.Async); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func lookupEnv(name string) (string, bool) {
+	return os.LookupEnv(EnvPrefix + name)
+}
+
+// parseEnvMap parses key=value,key=value
+func parseEnvMap(name, value string) (map[string]string, error) {
+	m := map[string]string{}
+
+	for _, kv := range strings.Split(value, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s%s %q: expected key=value", EnvPrefix, name, kv)
+		}
+
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return m, nil
+}
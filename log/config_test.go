@@ -0,0 +1,192 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type countHook struct {
+	count int
+}
+
+func (h *countHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *countHook) Fire(*logrus.Entry) error {
+	h.count++
+	return nil
+}
+
+func TestParseConfig(t *testing.T) {
+	want := Config{
+		LevelConfig: LevelConfig{Level: DebugLevel, Format: JSONFormatterType, Components: map[string]LevelLog{"webhook": TraceLevel}},
+		File:        FileConfig{Path: "/tmp/test.log", Format: JSONFormatterType, MaxSize: 10, MaxAge: 7, MaxBackup: 3},
+		Fields:      map[string]string{"cluster": "minikube", "app": "estore"},
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			data: `
+level: debug
+format: json
+components:
+  webhook: trace
+file:
+  path: /tmp/test.log
+  format: json
+  maxSize: 10
+  maxAge: 7
+  maxBackup: 3
+fields:
+  cluster: minikube
+  app: estore
+`,
+			want: want,
+		},
+		{
+			name: "json",
+			data: `{"level": "debug", "format": "json", "components": {"webhook": "trace"},
+				"file": {"path": "/tmp/test.log", "format": "json", "maxSize": 10, "maxAge": 7, "maxBackup": 3},
+				"fields": {"cluster": "minikube", "app": "estore"}}`,
+			want: want,
+		},
+		{name: "unknown key", data: `levle: debug`, wantErr: true},
+		{name: "invalid yaml", data: `level: [debug`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "config")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "log.yaml")
+	_ = ioutil.WriteFile(filename, []byte("level: warn\n"), 0600)
+
+	cfg, err := LoadConfigFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, WarnLevel, cfg.Level)
+
+	_, err = LoadConfigFile(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"GOUTILS_LOG_LEVEL":           "trace",
+		"GOUTILS_LOG_FORMAT":          "json",
+		"GOUTILS_LOG_COMPONENTS":      "webhook=debug, controller=info",
+		"GOUTILS_LOG_FIELDS":          "cluster=minikube,app=estore,",
+		"GOUTILS_LOG_FILE_PATH":       "/tmp/test.log",
+		"GOUTILS_LOG_FILE_FORMAT":     "text",
+		"GOUTILS_LOG_FILE_MAX_SIZE":   "10",
+		"GOUTILS_LOG_FILE_MAX_AGE":    "7",
+		"GOUTILS_LOG_FILE_MAX_BACKUP": "3",
+	}
+
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := Config{LevelConfig: LevelConfig{Level: InfoLevel}, File: FileConfig{MaxSize: 1}}
+	assert.Nil(t, cfg.LoadEnv())
+	assert.Equal(t, Config{
+		LevelConfig: LevelConfig{
+			Level: TraceLevel, Format: JSONFormatterType, Components: map[string]LevelLog{"webhook": DebugLevel, "controller": InfoLevel},
+		},
+		File:   FileConfig{Path: "/tmp/test.log", Format: TextFormatterType, MaxSize: 10, MaxAge: 7, MaxBackup: 3},
+		Fields: map[string]string{"cluster": "minikube", "app": "estore"},
+	}, cfg)
+
+	_ = os.Setenv("GOUTILS_LOG_FILE_MAX_AGE", "week")
+	assert.NotNil(t, cfg.LoadEnv())
+
+	_ = os.Setenv("GOUTILS_LOG_FILE_MAX_AGE", "7")
+	_ = os.Setenv("GOUTILS_LOG_FIELDS", "cluster")
+	assert.NotNil(t, cfg.LoadEnv())
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "empty", cfg: Config{}},
+		{name: "invalid level", cfg: Config{LevelConfig: LevelConfig{Level: "verbose"}}, wantErr: true},
+		{name: "invalid format", cfg: Config{LevelConfig: LevelConfig{Format: "xml"}}, wantErr: true},
+		{name: "invalid component level", cfg: Config{LevelConfig: LevelConfig{Components: map[string]LevelLog{"webhook": "x"}}}, wantErr: true},
+		{name: "invalid file format", cfg: Config{File: FileConfig{Path: "/tmp/test.log", Format: "xml"}}, wantErr: true},
+		{name: "negative rotation", cfg: Config{File: FileConfig{Path: "/tmp/test.log", MaxAge: -1}}, wantErr: true},
+		{name: "empty field", cfg: Config{Fields: map[string]string{"": "x"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, err := NewFromConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("NewFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "config")
+	defer os.RemoveAll(dir)
+
+	hook := &countHook{}
+	filename := filepath.Join(dir, "test.log")
+
+	cl, err := NewFromConfig(Config{
+		LevelConfig: LevelConfig{Level: InfoLevel, Components: map[string]LevelLog{"webhook": DebugLevel}},
+		File:        FileConfig{Path: filename, Format: JSONFormatterType, MaxSize: 1},
+		Fields:      map[string]string{ClusterKey: "minikube", ApplicationKey: "estore", "team": "store"},
+		Hooks:       []logrus.Hook{hook},
+	})
+	assert.Nil(t, err)
+
+	l := cl.(*Log)
+	assert.Equal(t, InfoLevel, l.GetLevel())
+	assert.Equal(t, []LevelRule{{Key: ComponentKey, Value: "webhook", Level: DebugLevel}}, l.GetFieldLevels())
+
+	logAndAssertJSON(t, l, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "minikube", fields["cluster"])
+		assert.Equal(t, "estore", fields["app"])
+		assert.Equal(t, "store", fields["team"])
+	})
+
+	l.Debug("filtered")
+	l.SetComponent("webhook").Debug("webhook debug")
+	assert.Equal(t, 2, hook.count)
+
+	data, _ := ioutil.ReadFile(filename)
+	assert.Contains(t, string(data), `"msg":"webhook debug"`)
+	assert.NotContains(t, string(data), "filtered")
+}
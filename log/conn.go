@@ -0,0 +1,90 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// writeTimeout time a write to the network sinks can take, dial and retry included, so a stalled peer does
+// not block the goroutines logging for long
+const writeTimeout = 5 * time.Second
+
+// redialDelay time after a failed dial the writes fail without dialing, while the peer is down
+const redialDelay = time.Second
+
+// connWriter writes to a connection opened on the first write, and opened again once
+// when a write fails (the peer restarted, the connection was closed, ...)
+type connWriter struct {
+	dial    func(timeout time.Duration) (net.Conn, error)
+	conn    net.Conn
+	timeout time.Duration
+
+	// the error of the last dial, returned until retryAt
+	dialErr error
+	retryAt time.Time
+}
+
+func newConnWriter(dial func(timeout time.Duration) (net.Conn, error)) *connWriter {
+	return &connWriter{dial: dial, timeout: writeTimeout}
+}
+
+// write writes the data in one call within the write timeout, the caller serializes the writes
+func (c *connWriter) write(data []byte) error {
+	var err error
+
+	deadline := time.Now().Add(c.timeout)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err = c.redial(deadline); err != nil {
+				return err
+			}
+		}
+
+		_ = c.conn.SetWriteDeadline(deadline)
+
+		if _, err = c.conn.Write(data); err == nil {
+			return nil
+		}
+
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	return err
+}
+
+// redial opens the connection before the deadline, unless the last dial failed less than redialDelay ago
+func (c *connWriter) redial(deadline time.Time) error {
+	now := time.Now()
+
+	if now.Before(c.retryAt) {
+		return c.dialErr
+	}
+
+	if !now.Before(deadline) {
+		return fmt.Errorf("write timeout of %v exceeded", c.timeout)
+	}
+
+	conn, err := c.dial(deadline.Sub(now))
+	if err != nil {
+		c.dialErr, c.retryAt = err, time.Now().Add(redialDelay)
+		return err
+	}
+
+	c.conn, c.dialErr, c.retryAt = conn, nil, time.Time{}
+
+	return nil
+}
+
+func (c *connWriter) close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+
+	return err
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnWriterStalledPeer(t *testing.T) {
+	var dials int
+
+	// the peer never reads
+	c := newConnWriter(func(timeout time.Duration) (net.Conn, error) {
+		dials++
+
+		client, _ := net.Pipe()
+
+		return client, nil
+	})
+	c.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := c.write([]byte("line"))
+
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second, time.Since(start))
+	assert.Equal(t, 1, dials)
+}
+
+func TestConnWriterRedial(t *testing.T) {
+	var timeouts []time.Duration
+
+	down := errors.New("connection refused")
+	c := newConnWriter(func(timeout time.Duration) (net.Conn, error) {
+		timeouts = append(timeouts, timeout)
+		return nil, down
+	})
+
+	assert.Equal(t, down, c.write([]byte("line")))
+
+	// no dial while the peer is down
+	assert.Equal(t, down, c.write([]byte("line")))
+
+	if assert.Len(t, timeouts, 1) {
+		assert.True(t, timeouts[0] <= writeTimeout)
+	}
+
+	c.retryAt = time.Now()
+	assert.Equal(t, down, c.write([]byte("line")))
+	assert.Len(t, timeouts, 2)
+}
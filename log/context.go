@@ -0,0 +1,121 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey int
+
+const (
+	loggerContextKey contextKey = iota
+	fieldsContextKey
+	requestIDContextKey
+	traceContextKey
+	firedContextKey
+)
+
+// ContextFields request scoped fields carried by a context.Context
+type ContextFields map[string]string
+
+var (
+	defaultLog     CommonLog
+	defaultLogOnce sync.Once
+)
+
+// IntoContext returns a copy of ctx carrying a snapshot of the logger.
+// The snapshot has its own push/pop stacks, so later changes to l are not seen by the context.
+func IntoContext(ctx context.Context, l CommonLog) context.Context {
+	return context.WithValue(ctx, loggerContextKey, l.ThreadLogger())
+}
+
+// FromContext returns a logger for the context.
+// The logger is a ThreadLogger of the one stored with IntoContext (or of a default logger when there is none)
+// with the fields stored with ContextWithFields and the trace of the context (see TraceFromContext) applied,
+// so it can be pushed/popped without affecting others.
+func FromContext(ctx context.Context) CommonLog {
+	base, ok := ctx.Value(loggerContextKey).(*Log)
+	if !ok {
+		base = getDefaultLog()
+	}
+
+	l := base.ThreadLogger()
+	for key, value := range FieldsFromContext(ctx) {
+		l.setContextField(key, value)
+	}
+
+	if t, ok := TraceFromContext(ctx); ok {
+		l.SetTrace(t)
+	}
+
+	return l
+}
+
+// ContextWithFields returns a copy of ctx carrying the fields merged with the ones already in ctx
+func ContextWithFields(ctx context.Context, fields ContextFields) context.Context {
+	merged := ContextFields{}
+
+	for key, value := range FieldsFromContext(ctx) {
+		merged[key] = value
+	}
+
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, fieldsContextKey, merged)
+}
+
+// FieldsFromContext returns the fields stored with ContextWithFields
+func FieldsFromContext(ctx context.Context) ContextFields {
+	fields, _ := ctx.Value(fieldsContextKey).(ContextFields)
+	return fields
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id, set by Middleware and sent by AuditTransport
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromContext returns the request id stored with ContextWithRequestID, empty when there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// DebugCtx logs at debug level using the logger and fields of the context
+func DebugCtx(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Debug(args...)
+}
+
+// InfoCtx logs at info level using the logger and fields of the context
+func InfoCtx(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Info(args...)
+}
+
+// WarnCtx logs at warn level using the logger and fields of the context
+func WarnCtx(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Warn(args...)
+}
+
+// ErrorCtx logs at error level using the logger and fields of the context
+func ErrorCtx(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Error(args...)
+}
+
+func getDefaultLog() *Log {
+	defaultLogOnce.Do(func() {
+		defaultLog = NewLogger()
+	})
+
+	return defaultLog.GetLogger()
+}
+
+// setContextField sets the field, an empty value removes the known context fields (cluster, app, ...)
+func (l *Log) setContextField(key, value string) {
+	if isContextKey(key) {
+		l.setStringField(key, value)
+	} else {
+		l.SetField(key, value)
+	}
+}
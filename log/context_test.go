@@ -0,0 +1,121 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntoFromContext(t *testing.T) {
+	logger := newLogger()
+	logger.SetCluster("ctxcluster").SetComponent("webhook")
+
+	ctx := IntoContext(context.Background(), logger)
+
+	// changes after IntoContext are not seen by the context
+	logger.SetComponent("controller")
+
+	l := FromContext(ctx).(*Log)
+	logAndAssertJSON(t, l, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "ctxcluster", fields["cluster"])
+		assert.Equal(t, "webhook", fields["component"])
+	})
+}
+
+func TestContextWithFields(t *testing.T) {
+	logger := newLogger()
+	logger.SetCluster("ctxcluster").SetOperation("create")
+
+	ctx := IntoContext(context.Background(), logger)
+	ctx = ContextWithFields(ctx, ContextFields{UserKey: "johnny", OperationKey: "update"})
+	ctx = ContextWithFields(ctx, ContextFields{ObjectNameKey: "iphone", "requestID": "req1"})
+
+	assert.Equal(t, ContextFields{
+		UserKey: "johnny", OperationKey: "update", ObjectNameKey: "iphone", "requestID": "req1",
+	}, FieldsFromContext(ctx))
+
+	l := FromContext(ctx).(*Log)
+	logAndAssertJSON(t, l, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "ctxcluster", fields["cluster"])
+		assert.Equal(t, "johnny", fields["user"])
+		assert.Equal(t, "update", fields["operation"])
+		assert.Equal(t, "iphone", fields["objectName"])
+		assert.Equal(t, "req1", fields["requestID"])
+	})
+
+	// original logger is untouched
+	logAndAssertJSON(t, logger, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "create", fields["operation"])
+		assert.Nil(t, fields["user"])
+	})
+}
+
+func TestFromContextScoping(t *testing.T) {
+	ctx := IntoContext(context.Background(), newLogger().SetComponent("webhook"))
+
+	l1 := FromContext(ctx).(*Log)
+	l2 := FromContext(ctx).(*Log)
+
+	l1.PushContext()
+	l1.SetStep("mutate")
+	assert.Equal(t, 1, l1.contextStack.Len())
+	assert.Equal(t, 0, l2.contextStack.Len())
+
+	logAndAssertJSON(t, l2, "test", func(fields logrus.Fields) {
+		assert.Nil(t, fields["step"])
+	})
+
+	l1.PopContext()
+	logAndAssertJSON(t, l1, "test", func(fields logrus.Fields) {
+		assert.Nil(t, fields["step"])
+		assert.Equal(t, "webhook", fields["component"])
+	})
+}
+
+func TestCtxLevels(t *testing.T) {
+	out := &MemoryWriter{}
+
+	logger := newLogger().SetLevel(DebugLevel).SetFormatterType(JSONFormatterType)
+	logger.logger.Out = out
+
+	trace := NewTraceContext()
+	ctx := ContextWithFields(IntoContext(context.Background(), logger), ContextFields{UserKey: "johnny", RequestIDKey: "r1"})
+	ctx = ContextWithTrace(ctx, trace)
+
+	DebugCtx(ctx, "debug")
+	InfoCtx(ctx, "info")
+	WarnCtx(ctx, "warn")
+	ErrorCtx(ctx, "error")
+
+	lines := jsonLines(out)
+	if assert.Len(t, lines, 4) {
+		for i, level := range []string{"debug", "info", "warning", "error"} {
+			assert.Equal(t, level, lines[i]["level"])
+			assert.Equal(t, strings.TrimSuffix(level, "ing"), lines[i]["msg"])
+			assert.Equal(t, "johnny", lines[i]["user"])
+			assert.Equal(t, "r1", lines[i][RequestIDKey])
+			assert.Equal(t, trace.TraceID, lines[i][TraceIDKey])
+			assert.Equal(t, trace.SpanID, lines[i][SpanIDKey])
+		}
+	}
+
+	// no logger in context falls back to the default logger
+	def := &MemoryWriter{}
+	assert.NoError(t, getDefaultLog().AddSink(Sink{Name: "ctxlevels", Writer: def}))
+
+	defer func() { _ = getDefaultLog().RemoveSink("ctxlevels") }()
+
+	InfoCtx(ContextWithFields(context.Background(), ContextFields{UserKey: "johnny"}), "default")
+
+	lines = jsonLines(def)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "info", lines[0]["level"])
+		assert.Equal(t, "default", lines[0]["msg"])
+		assert.Equal(t, "johnny", lines[0]["user"])
+	}
+
+	assert.Len(t, out.Lines(), 4)
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arutselvan15/go-utils/logconstants"
+	"github.com/sirupsen/logrus"
+)
+
+// ECSVersion version of the Elastic Common Schema written by the ECSFormatter
+const ECSVersion = "1.6.0"
+
+// ecsFields ECS names of the fields, the other fields are written as is
+var ecsFields = map[string]string{
+	ClusterKey:      "orchestrator.cluster.name",
+	ApplicationKey:  "service.name",
+	UserKey:         "user.name",
+	OperationKey:    "event.action",
+	"auditType":     "event.category",
+	"httpType":      "http.request.method",
+	"request":       "http.request.body.content",
+	"response":      "http.response.body.content",
+	"responseCode":  "http.response.status_code",
+	logrus.ErrorKey: "error.message",
+	ObjectStateKey:  "labels.objectState",
+}
+
+// ECSFormatter formats entries as Elastic Common Schema json documents
+type ECSFormatter struct {
+	// TimestampFormat @timestamp format, RFC3339 with nanoseconds in UTC when empty
+	TimestampFormat string
+}
+
+// Format formats the entry on one line
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339Nano
+	}
+
+	doc := map[string]interface{}{
+		"@timestamp": entry.Time.UTC().Format(timestampFormat),
+		"message":    entry.Message,
+	}
+
+	setECSField(doc, "log.level", entry.Level.String())
+	setECSField(doc, "ecs.version", ECSVersion)
+
+	if entry.HasCaller() {
+		setECSField(doc, "log.origin.function", entry.Caller.Function)
+		setECSField(doc, "log.origin.file.name", entry.Caller.File)
+		setECSField(doc, "log.origin.file.line", entry.Caller.Line)
+	}
+
+	// the ECS fields first, the other fields can not replace them whatever the order of the map
+	var others []string
+
+	for key, value := range entry.Data {
+		value = ecsValue(value)
+
+		if key == ObjectStateKey {
+			setECSField(doc, "event.outcome", ecsOutcome(fmt.Sprint(value)))
+		}
+
+		switch name, ok := ecsFields[key]; {
+		case ok:
+			setECSField(doc, name, value)
+		case key == "endpoint":
+			setECSURL(doc, fmt.Sprint(value))
+		default:
+			others = append(others, key)
+		}
+	}
+
+	sort.Strings(others)
+
+	for _, key := range others {
+		value := ecsValue(entry.Data[key])
+
+		if !isECSFieldFree(doc, key) {
+			// clashing fields are kept as fields.<key> like the logrus json formatter does
+			key = "fields." + key
+		}
+
+		setECSField(doc, key, value)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+// ecsValue returns the message of the errors, the value otherwise
+func ecsValue(value interface{}) interface{} {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+
+	return value
+}
+
+// ecsOutcome maps the object state to the ECS event outcome (success, failure or unknown)
+func ecsOutcome(state string) string {
+	switch state {
+	case logconstants.Successful:
+		return "success"
+	case logconstants.Failed:
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
+
+// setECSURL sets the url.path and url.query of the endpoint, with url.scheme and url.domain for the full urls
+func setECSURL(doc map[string]interface{}, endpoint string) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		setECSField(doc, "url.original", endpoint)
+		return
+	}
+
+	setECSField(doc, "url.path", u.Path)
+
+	if u.RawQuery != "" {
+		setECSField(doc, "url.query", u.RawQuery)
+	}
+
+	if u.Host != "" {
+		setECSField(doc, "url.scheme", u.Scheme)
+		setECSField(doc, "url.domain", u.Hostname())
+	}
+}
+
+// isECSFieldFree checks the dotted name is not set and none of its parents is a value
+func isECSFieldFree(doc map[string]interface{}, name string) bool {
+	parts := strings.Split(name, ".")
+	m := doc
+
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := m[part]
+		if !ok {
+			return true
+		}
+
+		if m, ok = child.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+
+	_, ok := m[parts[len(parts)-1]]
+
+	return !ok
+}
+
+// setECSField sets the dotted name as nested objects, as a flat key when a parent is already a value
+func setECSField(doc map[string]interface{}, name string, value interface{}) {
+	parts := strings.Split(name, ".")
+	m := doc
+
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := m[part]
+		if !ok {
+			child = map[string]interface{}{}
+			m[part] = child
+		}
+
+		cm, ok := child.(map[string]interface{})
+		if !ok {
+			doc[name] = value
+			return
+		}
+
+		m = cm
+	}
+
+	m[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arutselvan15/go-utils/logconstants"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECSFormatter(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 0, time.FixedZone("IST", 19800))
+
+	tests := []struct {
+		name   string
+		level  logrus.Level
+		fields logrus.Fields
+		want   string
+	}{
+		{
+			name: "no fields", level: logrus.InfoLevel,
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","ecs":{"version":"1.6.0"},"log":{"level":"info"},"message":"test"}`,
+		},
+		{
+			name: "context fields", level: logrus.WarnLevel,
+			fields: logrus.Fields{
+				ClusterKey: "minikube", ApplicationKey: "estore", UserKey: "johnny", OperationKey: "create",
+				ObjectStateKey: logconstants.Failed, "auditType": "object", ComponentKey: "controller",
+				"error": errors.New("failed"),
+			},
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","component":"controller","ecs":{"version":"1.6.0"},` +
+				`"error":{"message":"failed"},"event":{"action":"create","category":"object","outcome":"failure"},` +
+				`"labels":{"objectState":"failed"},"log":{"level":"warning"},"message":"test",` +
+				`"orchestrator":{"cluster":{"name":"minikube"}},"service":{"name":"estore"},"user":{"name":"johnny"}}`,
+		},
+		{
+			name: "audit api", level: logrus.InfoLevel,
+			fields: logrus.Fields{
+				"auditType": "api", "httpType": "POST", "endpoint": "/products?dry=true", "responseCode": 201,
+				"request": `{"name":"iphone"}`,
+			},
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","ecs":{"version":"1.6.0"},"event":{"category":"api"},` +
+				`"http":{"request":{"body":{"content":"{\"name\":\"iphone\"}"},"method":"POST"},` +
+				`"response":{"status_code":201}},"log":{"level":"info"},"message":"test",` +
+				`"url":{"path":"/products","query":"dry=true"}}`,
+		},
+		{
+			name: "dotted and clashing fields", level: logrus.InfoLevel,
+			fields: logrus.Fields{"ecs.extra": "x", "message": "clash"},
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","ecs":{"extra":"x","version":"1.6.0"},` +
+				`"fields":{"message":"clash"},"log":{"level":"info"},"message":"test"}`,
+		},
+		{
+			name: "ecs object fields", level: logrus.InfoLevel,
+			fields: logrus.Fields{"event": "plain", "log": "plain", "ecs": "plain", OperationKey: "create"},
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","ecs":{"version":"1.6.0"},"event":{"action":"create"},` +
+				`"fields":{"ecs":"plain","event":"plain","log":"plain"},"log":{"level":"info"},"message":"test"}`,
+		},
+		{
+			name: "full url", level: logrus.InfoLevel,
+			fields: logrus.Fields{"endpoint": "https://api.example.com:8443/products/1?dry=true&token=[REDACTED]"},
+			want: `{"@timestamp":"2019-08-01T09:34:05Z","ecs":{"version":"1.6.0"},"log":{"level":"info"},` +
+				`"message":"test","url":{"domain":"api.example.com","path":"/products/1",` +
+				`"query":"dry=true\u0026token=[REDACTED]","scheme":"https"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields).WithTime(ts)
+			entry.Level = tt.level
+			entry.Message = "test"
+
+			got, err := (&ECSFormatter{}).Format(entry)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want+"\n", string(got))
+		})
+	}
+}
+
+func TestECSOutcome(t *testing.T) {
+	assert.Equal(t, "success", ecsOutcome(logconstants.Successful))
+	assert.Equal(t, "failure", ecsOutcome(logconstants.Failed))
+	assert.Equal(t, "unknown", ecsOutcome(logconstants.Processing))
+}
+
+func TestECSFormatterType(t *testing.T) {
+	var buffer bytes.Buffer
+
+	memory := &MemoryWriter{}
+	logger := newLogger()
+	logger.logger.Out = &buffer
+
+	assert.Nil(t, logger.AddSink(Sink{Name: "ecs", Writer: memory, Format: ECSFormatterType}))
+	logger.SetFormatterType(ECSFormatterType).SetLevel(DebugLevel)
+	logger.SetCluster("minikube").SetUser("johnny").LogAuditAPI("GET", "/products", "", "", 200)
+
+	lines := append([]string{buffer.String()}, memory.Lines()...)
+	assert.Len(t, lines, 2)
+
+	for _, line := range lines {
+		var doc map[string]interface{}
+
+		assert.Nil(t, json.Unmarshal([]byte(line), &doc))
+		assert.Equal(t, map[string]interface{}{"name": "minikube"}, doc["orchestrator"].(map[string]interface{})["cluster"])
+		assert.Equal(t, map[string]interface{}{"name": "johnny"}, doc["user"])
+		assert.Equal(t, "GET", doc["http"].(map[string]interface{})["request"].(map[string]interface{})["method"])
+		assert.Equal(t, float64(200), doc["http"].(map[string]interface{})["response"].(map[string]interface{})["status_code"])
+		assert.Equal(t, map[string]interface{}{"path": "/products"}, doc["url"])
+		assert.Contains(t, doc, "@timestamp")
+	}
+}
@@ -0,0 +1,224 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang-collections/collections/stack"
+	"github.com/sirupsen/logrus"
+)
+
+// The logging methods of the embedded entry are wrapped to read the entry of the context under lock,
+// the setters replace it while other goroutines log.
+
+// syncStack stack safe for concurrent use
+type syncStack struct {
+	mu    sync.Mutex
+	stack *stack.Stack
+}
+
+func newSyncStack(s *stack.Stack) *syncStack {
+	return &syncStack{stack: s}
+}
+
+func (s *syncStack) push(value interface{}) {
+	s.mu.Lock()
+	s.stack.Push(value)
+	s.mu.Unlock()
+}
+
+// Len returns the number of values in the stack
+func (s *syncStack) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stack.Len()
+}
+
+// pop returns false when the stack is empty
+func (s *syncStack) pop() (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stack.Len() == 0 {
+		return nil, false
+	}
+
+	return s.stack.Pop(), true
+}
+
+// WithError WithError
+func (l *Log) WithError(err error) *logrus.Entry {
+	return l.entry().WithError(err)
+}
+
+// WithContext WithContext
+func (l *Log) WithContext(ctx context.Context) *logrus.Entry {
+	return l.entry().WithContext(ctx)
+}
+
+// WithField WithField
+func (l *Log) WithField(key string, value interface{}) *logrus.Entry {
+	return l.entry().WithField(key, value)
+}
+
+// WithFields WithFields
+func (l *Log) WithFields(fields logrus.Fields) *logrus.Entry {
+	return l.entry().WithFields(fields)
+}
+
+// WithTime WithTime
+func (l *Log) WithTime(t time.Time) *logrus.Entry {
+	return l.entry().WithTime(t)
+}
+
+// Log Log
+func (l *Log) Log(level logrus.Level, args ...interface{}) {
+	l.entry().Log(level, args...)
+}
+
+// Logf Logf
+func (l *Log) Logf(level logrus.Level, format string, args ...interface{}) {
+	l.entry().Logf(level, format, args...)
+}
+
+// Logln Logln
+func (l *Log) Logln(level logrus.Level, args ...interface{}) {
+	l.entry().Logln(level, args...)
+}
+
+// Trace Trace
+func (l *Log) Trace(args ...interface{}) {
+	l.entry().Trace(args...)
+}
+
+// Debug Debug
+func (l *Log) Debug(args ...interface{}) {
+	l.entry().Debug(args...)
+}
+
+// Print Print
+func (l *Log) Print(args ...interface{}) {
+	l.entry().Print(args...)
+}
+
+// Info Info
+func (l *Log) Info(args ...interface{}) {
+	l.entry().Info(args...)
+}
+
+// Warn Warn
+func (l *Log) Warn(args ...interface{}) {
+	l.entry().Warn(args...)
+}
+
+// Warning Warning
+func (l *Log) Warning(args ...interface{}) {
+	l.entry().Warning(args...)
+}
+
+// Error Error
+func (l *Log) Error(args ...interface{}) {
+	l.entry().Error(args...)
+}
+
+// Fatal Fatal
+func (l *Log) Fatal(args ...interface{}) {
+	l.entry().Fatal(args...)
+}
+
+// Panic Panic
+func (l *Log) Panic(args ...interface{}) {
+	l.entry().Panic(args...)
+}
+
+// Tracef Tracef
+func (l *Log) Tracef(format string, args ...interface{}) {
+	l.entry().Tracef(format, args...)
+}
+
+// Debugf Debugf
+func (l *Log) Debugf(format string, args ...interface{}) {
+	l.entry().Debugf(format, args...)
+}
+
+// Printf Printf
+func (l *Log) Printf(format string, args ...interface{}) {
+	l.entry().Printf(format, args...)
+}
+
+// Infof Infof
+func (l *Log) Infof(format string, args ...interface{}) {
+	l.entry().Infof(format, args...)
+}
+
+// Warnf Warnf
+func (l *Log) Warnf(format string, args ...interface{}) {
+	l.entry().Warnf(format, args...)
+}
+
+// Warningf Warningf
+func (l *Log) Warningf(format string, args ...interface{}) {
+	l.entry().Warningf(format, args...)
+}
+
+// Errorf Errorf
+func (l *Log) Errorf(format string, args ...interface{}) {
+	l.entry().Errorf(format, args...)
+}
+
+// Fatalf Fatalf
+func (l *Log) Fatalf(format string, args ...interface{}) {
+	l.entry().Fatalf(format, args...)
+}
+
+// Panicf Panicf
+func (l *Log) Panicf(format string, args ...interface{}) {
+	l.entry().Panicf(format, args...)
+}
+
+// Traceln Traceln
+func (l *Log) Traceln(args ...interface{}) {
+	l.entry().Traceln(args...)
+}
+
+// Debugln Debugln
+func (l *Log) Debugln(args ...interface{}) {
+	l.entry().Debugln(args...)
+}
+
+// Println Println
+func (l *Log) Println(args ...interface{}) {
+	l.entry().Println(args...)
+}
+
+// Infoln Infoln
+func (l *Log) Infoln(args ...interface{}) {
+	l.entry().Infoln(args...)
+}
+
+// Warnln Warnln
+func (l *Log) Warnln(args ...interface{}) {
+	l.entry().Warnln(args...)
+}
+
+// Warningln Warningln
+func (l *Log) Warningln(args ...interface{}) {
+	l.entry().Warningln(args...)
+}
+
+// Errorln Errorln
+func (l *Log) Errorln(args ...interface{}) {
+	l.entry().Errorln(args...)
+}
+
+// Fatalln Fatalln
+func (l *Log) Fatalln(args ...interface{}) {
+	l.entry().Fatalln(args...)
+}
+
+// Panicln Panicln
+func (l *Log) Panicln(args ...interface{}) {
+	l.entry().Panicln(args...)
+}
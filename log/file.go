@@ -0,0 +1,39 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func newFileWriter(cfg FileConfig) (*rotatingWriter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("invalid log file: empty path")
+	}
+
+	if err := validatePattern(cfg.Path); err != nil {
+		return nil, err
+	}
+
+	writer := newRotatingWriter(cfg)
+	if err := checkLogFile(writer.writer.Filename); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// checkLogFile makes sure the log file can be created and written
+func checkLogFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0744); err != nil {
+		return fmt.Errorf("invalid log file %s: %v", filename, err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("invalid log file %s: %v", filename, err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoggerWithFileE(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "file")
+	defer os.RemoveAll(dir)
+
+	notDir := filepath.Join(dir, "file")
+	_ = ioutil.WriteFile(notDir, []byte{}, 0600)
+
+	tests := []struct {
+		name     string
+		filename string
+		wantErr  bool
+	}{
+		{name: "success", filename: filepath.Join(dir, "test.log")},
+		{name: "success new dir", filename: filepath.Join(dir, "logs", "test.log")},
+		{name: "empty path", filename: "", wantErr: true},
+		{name: "parent is a file", filename: filepath.Join(notDir, "test.log"), wantErr: true},
+		{name: "path is a dir", filename: dir, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLoggerWithFileE(tt.filename, 1, 1, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLoggerWithFileE() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr {
+				got.Info("test log")
+
+				data, _ := ioutil.ReadFile(tt.filename)
+				assert.Contains(t, string(data), "test log")
+			}
+		})
+	}
+
+	// errors are logged without the error constructor
+	assert.NotNil(t, NewLoggerWithFile(filepath.Join(notDir, "test.log"), 1, 1, 1))
+}
+
+func TestSetLogFileFormatterType(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "file")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+	logger := NewLoggerWithFile(filename, 1, 1, 1).(*Log)
+	logger.logger.Out = ioutil.Discard
+
+	logger.Info("text line")
+	logger.SetLogFileFormatterType(JSONFormatterType)
+	logger.SetLogFileFormatterType(JSONFormatterType)
+	logger.ThreadLogger().Info("json line")
+	logger.SetLogFileFormatterType(TextFormatterType)
+	logger.Info("text again")
+
+	data, _ := ioutil.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], `msg="text line"`)
+	assert.Contains(t, lines[1], `"msg":"json line"`)
+	assert.Contains(t, lines[2], `msg="text again"`)
+}
@@ -0,0 +1,318 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// GELFVersion version of the GELF payloads written by the GELFFormatter
+	GELFVersion = "1.1"
+	// DefaultGELFChunkSize UDP chunk size used when none is configured
+	DefaultGELFChunkSize = 1420
+
+	gelfMaxChunks   = 128
+	gelfChunkHeader = 12
+)
+
+// GELFCompression compression of the GELF UDP datagrams
+type GELFCompression string
+
+var (
+	// GELFGzip GELFGzip
+	GELFGzip GELFCompression = "gzip"
+	// GELFZlib GELFZlib
+	GELFZlib GELFCompression = "zlib"
+	// GELFNoCompression GELFNoCompression
+	GELFNoCompression GELFCompression = "none"
+)
+
+// GELFConfig Graylog (GELF) output configuration
+type GELFConfig struct {
+	// Network udp or tcp
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// Address host:port of the GELF input
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Level most verbose level sent, the level of the logger when empty
+	Level LevelLog `json:"level,omitempty" yaml:"level,omitempty"`
+	// Compression UDP compression, gzip when empty (TCP is never compressed)
+	Compression GELFCompression `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// ChunkSize max UDP datagram size, DefaultGELFChunkSize when 0
+	ChunkSize int `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`
+}
+
+// GELFFormatter formats entries as GELF 1.1 json payloads, the fields are sent as _ prefixed additional fields
+type GELFFormatter struct {
+	// Host host field, the hostname when empty
+	Host string
+}
+
+var (
+	hostnameOnce sync.Once
+	hostname     string
+)
+
+// Format formats the entry on one line
+func (f *GELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	host := f.Host
+	if host == "" {
+		host = defaultHostname()
+	}
+
+	short, full := entry.Message, ""
+	if i := strings.IndexByte(entry.Message, '\n'); i >= 0 {
+		short, full = entry.Message[:i], entry.Message
+	}
+
+	msg := map[string]interface{}{
+		"version":       GELFVersion,
+		"host":          host,
+		"short_message": short,
+		"timestamp":     float64(entry.Time.UnixNano()/int64(time.Millisecond)) / 1000,
+		"level":         syslogSeverity(entry.Level),
+	}
+
+	if full != "" {
+		msg["full_message"] = full
+	}
+
+	if entry.HasCaller() {
+		msg["_"+logrus.FieldKeyFunc] = entry.Caller.Function
+		msg["_"+logrus.FieldKeyFile] = fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+	}
+
+	for key, value := range entry.Data {
+		msg[gelfFieldName(key)] = gelfValue(value)
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+// gelfFieldName returns the additional field name of the key, _id is reserved by GELF
+func gelfFieldName(key string) string {
+	name := []byte("_" + key)
+
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			name[i] = '_'
+		}
+	}
+
+	if string(name) == "_id" {
+		return "_id_"
+	}
+
+	return string(name)
+}
+
+// gelfValue returns the value as a number or a string, the only types of the additional fields
+func gelfValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func defaultHostname() string {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "localhost"
+		}
+	})
+
+	return hostname
+}
+
+// NewGELFSink creates a sink sending GELF payloads to a Graylog UDP or TCP input,
+// the connection is opened on the first entry and opened again after a failure
+func NewGELFSink(name string, cfg GELFConfig) (Sink, error) {
+	writer, err := newGELFWriter(cfg)
+	if err != nil {
+		return Sink{}, err
+	}
+
+	return Sink{Name: name, Writer: writer, Level: cfg.Level, Format: GELFFormatterType}, nil
+}
+
+// gelfWriter sends every write as a GELF message: compressed and chunked datagrams over UDP,
+// null delimited frames over TCP
+type gelfWriter struct {
+	mu   sync.Mutex
+	cfg  GELFConfig
+	conn *connWriter
+}
+
+func newGELFWriter(cfg GELFConfig) (*gelfWriter, error) {
+	if cfg.Network != "udp" && cfg.Network != "tcp" {
+		return nil, fmt.Errorf("invalid gelf network %q: expected udp or tcp", cfg.Network)
+	}
+
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("invalid gelf config: empty address")
+	}
+
+	if cfg.Compression == "" {
+		cfg.Compression = GELFGzip
+	}
+
+	if cfg.Compression != GELFGzip && cfg.Compression != GELFZlib && cfg.Compression != GELFNoCompression {
+		return nil, fmt.Errorf("invalid gelf compression %q", cfg.Compression)
+	}
+
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = DefaultGELFChunkSize
+	}
+
+	if cfg.ChunkSize <= gelfChunkHeader {
+		return nil, fmt.Errorf("invalid gelf chunk size %d", cfg.ChunkSize)
+	}
+
+	dial := func(timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout(cfg.Network, cfg.Address, timeout)
+	}
+
+	return &gelfWriter{cfg: cfg, conn: newConnWriter(dial)}, nil
+}
+
+// Write sends the message, the trailing newline of the formatter is removed
+func (w *gelfWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	if w.cfg.Network == "udp" {
+		err = w.writeUDP(msg)
+	} else {
+		err = w.conn.write(append(append([]byte{}, msg...), 0))
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("gelf %s %s: %v", w.cfg.Network, w.cfg.Address, err)
+	}
+
+	return len(p), nil
+}
+
+// Close closes the connection
+func (w *gelfWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.conn.close()
+}
+
+// writeUDP sends the compressed message in one datagram, or in chunks when larger than the chunk size
+func (w *gelfWriter) writeUDP(msg []byte) error {
+	data, err := w.compress(msg)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := gelfChunks(data, w.cfg.ChunkSize)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		if err := w.conn.write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *gelfWriter) compress(msg []byte) ([]byte, error) {
+	var (
+		b  bytes.Buffer
+		zw io.WriteCloser
+	)
+
+	switch w.cfg.Compression {
+	case GELFGzip:
+		zw = gzip.NewWriter(&b)
+	case GELFZlib:
+		zw = zlib.NewWriter(&b)
+	default:
+		return msg, nil
+	}
+
+	if _, err := zw.Write(msg); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// gelfChunks splits the datagram in GELF chunks when it is larger than the chunk size:
+// magic bytes 0x1e 0x0f, 8 bytes message id, sequence number, sequence count, data
+func gelfChunks(data []byte, chunkSize int) ([][]byte, error) {
+	if len(data) <= chunkSize {
+		return [][]byte{data}, nil
+	}
+
+	size := chunkSize - gelfChunkHeader
+	count := (len(data) + size - 1) / size
+
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("message too large: %d bytes in more than %d chunks", len(data), gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunk := make([]byte, 0, gelfChunkHeader+end-i*size)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*size:end]...)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
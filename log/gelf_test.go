@@ -0,0 +1,256 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGELFFormatter(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name    string
+		level   logrus.Level
+		message string
+		fields  logrus.Fields
+		want    string
+	}{
+		{
+			name: "no fields", level: logrus.InfoLevel, message: "test",
+			want: `{"host":"node1","level":6,"short_message":"test","timestamp":1564671845.123,"version":"1.1"}`,
+		},
+		{
+			name: "additional fields", level: logrus.ErrorLevel, message: "test",
+			fields: logrus.Fields{
+				ClusterKey: "minikube", UserKey: "johnny", "responseCode": 201, "error": errors.New("failed"),
+				"id": "1", "bad key": true, "object": map[string]string{"a": "b"},
+			},
+			want: `{"_bad_key":"true","_cluster":"minikube","_error":"failed","_id_":"1","_object":"{\"a\":\"b\"}",` +
+				`"_responseCode":201,"_user":"johnny","host":"node1","level":3,"short_message":"test",` +
+				`"timestamp":1564671845.123,"version":"1.1"}`,
+		},
+		{
+			name: "full message", level: logrus.DebugLevel, message: "first\nsecond",
+			want: `{"full_message":"first\nsecond","host":"node1","level":7,"short_message":"first",` +
+				`"timestamp":1564671845.123,"version":"1.1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields).WithTime(ts)
+			entry.Level = tt.level
+			entry.Message = tt.message
+
+			got, err := (&GELFFormatter{Host: "node1"}).Format(entry)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want+"\n", string(got))
+		})
+	}
+}
+
+func TestNewGELFSinkInvalid(t *testing.T) {
+	for _, cfg := range []GELFConfig{
+		{Network: "http", Address: "localhost:12201"},
+		{Network: "udp"},
+		{Network: "udp", Address: "localhost:12201", Compression: "lz4"},
+		{Network: "udp", Address: "localhost:12201", ChunkSize: 12},
+	} {
+		_, err := NewGELFSink("graylog", cfg)
+		assert.NotNil(t, err, "%+v", cfg)
+	}
+}
+
+func TestGELFSinkUDP(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression GELFCompression
+		chunkSize   int
+		decompress  func([]byte) ([]byte, error)
+	}{
+		{name: "gzip", compression: GELFGzip, decompress: gunzip},
+		{name: "zlib", compression: GELFZlib, decompress: unzlib},
+		{name: "none", compression: GELFNoCompression, decompress: func(b []byte) ([]byte, error) { return b, nil }},
+		{name: "chunked", compression: GELFNoCompression, chunkSize: 64,
+			decompress: func(b []byte) ([]byte, error) { return b, nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			assert.Nil(t, err)
+			defer conn.Close()
+
+			sink, err := NewGELFSink("graylog", GELFConfig{
+				Network: "udp", Address: conn.LocalAddr().String(), Compression: tt.compression, ChunkSize: tt.chunkSize,
+			})
+			assert.Nil(t, err)
+
+			logger := newLogger()
+			logger.logger.Out = ioutil.Discard
+			assert.Nil(t, logger.AddSink(sink))
+			defer logger.RemoveSink("graylog")
+
+			logger.SetCluster("minikube").SetUser("johnny").Info(strings.Repeat("a", 100))
+
+			data := readGELFDatagram(t, conn)
+			msg, err := tt.decompress(data)
+			assert.Nil(t, err)
+
+			var got map[string]interface{}
+
+			assert.Nil(t, json.Unmarshal(msg, &got))
+			assert.Equal(t, "minikube", got["_cluster"])
+			assert.Equal(t, "johnny", got["_user"])
+			assert.Equal(t, strings.Repeat("a", 100), got["short_message"])
+			assert.Equal(t, float64(6), got["level"])
+		})
+	}
+}
+
+func TestGELFSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	frames := make(chan string, 4)
+
+	go func() {
+		// the first connection is closed after one frame to check the sink connects again
+		for i := 0; i < 2; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			for {
+				frame, err := r.ReadString(0)
+				if err != nil {
+					break
+				}
+
+				frames <- frame
+
+				if i == 0 {
+					break
+				}
+			}
+
+			conn.Close()
+		}
+	}()
+
+	sink, err := NewGELFSink("graylog", GELFConfig{Network: "tcp", Address: ln.Addr().String()})
+	assert.Nil(t, err)
+
+	logger := newLogger()
+	logger.logger.Out = ioutil.Discard
+	assert.Nil(t, logger.AddSink(sink))
+	defer logger.RemoveSink("graylog")
+
+	logger.SetComponent("webhook").Info("first")
+	assert.Contains(t, <-frames, `"short_message":"first"`)
+
+	// writes to the closed connection may succeed once before failing
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		logger.Info("second")
+
+		select {
+		case frame := <-frames:
+			assert.True(t, strings.HasSuffix(frame, "}\x00"))
+			assert.Contains(t, frame, `"_component":"webhook"`)
+			assert.Contains(t, frame, `"short_message":"second"`)
+
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("no frame received after reconnect")
+}
+
+func TestGELFChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 100)
+
+	chunks, err := gelfChunks(data, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{data}, chunks)
+
+	chunks, err = gelfChunks(data, 52)
+	assert.Nil(t, err)
+	assert.Len(t, chunks, 3)
+
+	for i, chunk := range chunks {
+		assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+		assert.Equal(t, chunks[0][2:10], chunk[2:10])
+		assert.Equal(t, []byte{byte(i), 3}, chunk[10:12])
+	}
+
+	_, err = gelfChunks(bytes.Repeat([]byte("x"), 129*10), 22)
+	assert.NotNil(t, err)
+}
+
+// readGELFDatagram reads a datagram, reassembling the chunks
+func readGELFDatagram(t *testing.T, conn net.PacketConn) []byte {
+	var (
+		parts [][]byte
+		count = 1
+		buf   = make([]byte, 65536)
+	)
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for received := 0; received < count; received++ {
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.Nil(t, err) {
+			return nil
+		}
+
+		datagram := append([]byte{}, buf[:n]...)
+		if n < 2 || datagram[0] != 0x1e || datagram[1] != 0x0f {
+			return datagram
+		}
+
+		if parts == nil {
+			count = int(datagram[11])
+			parts = make([][]byte, count)
+		}
+
+		parts[datagram[10]] = datagram[12:]
+	}
+
+	return bytes.Join(parts, nil)
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+func unzlib(b []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
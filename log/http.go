@@ -0,0 +1,344 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arutselvan15/go-utils/logconstants"
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// audit api field keys
+const (
+	// RequestIDKey RequestIDKey
+	RequestIDKey = "requestId"
+	// LatencyKey LatencyKey
+	LatencyKey = "latency"
+	// BytesKey BytesKey
+	BytesKey = "bytes"
+)
+
+// RequestIDHeader default header of the request id
+const RequestIDHeader = "X-Request-Id"
+
+// DefaultMaxBodySize default bytes of the request and response bodies kept in the audit line
+const DefaultMaxBodySize = 4096
+
+// truncatedSuffix appended to the bodies over the size limit
+const truncatedSuffix = "...(truncated)"
+
+// UserExtractor returns the user of the request, empty when unknown
+type UserExtractor func(r *http.Request) string
+
+// HTTPConfig configuration of the http middleware and transport
+type HTTPConfig struct {
+	// User returns the user of the request, the basic auth user when nil (middleware only)
+	User UserExtractor
+	// RequestIDHeader header of the request id, RequestIDHeader when empty
+	RequestIDHeader string
+	// MaxBodySize bytes of the request and response bodies logged, DefaultMaxBodySize when 0, no body when negative
+	MaxBodySize int
+	// Redactor redacts the bodies, redact.Default() when nil
+	Redactor *redact.Redactor
+	// Level level of the audit line, debug (like LogAuditAPI) when empty
+	Level LevelLog
+}
+
+// Middleware returns a net/http middleware logging every request with a ThreadLogger of the logger.
+// The logger has the user, the operation (create, read, update, delete from the method), the request id
+// (read from the request id header or generated, and set on the response) and the trace (see ServerTrace with
+// the traceparent header) fields, it is stored in the request context for the handler (see FromContext).
+// When the handler returns, an audit line like LogAuditAPI is written with the status code, latency, response
+// bytes and size limited, redacted bodies; the status is 500 when it panics, the panic goes on.
+func Middleware(l CommonLog, cfg HTTPConfig) func(http.Handler) http.Handler {
+	cfg = cfg.withDefaults()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(cfg.RequestIDHeader)
+			if id == "" {
+				id = NewRequestID()
+			}
+
+			trace := ServerTrace(r.Context(), r.Header.Get(TraceparentHeader))
+
+			tl := l.ThreadLogger()
+			tl.SetOperation(httpOperation(r.Method)).SetField(RequestIDKey, id).SetTrace(trace)
+
+			if user := cfg.User(r); user != "" {
+				tl.SetUser(user)
+			}
+
+			w.Header().Set(cfg.RequestIDHeader, id)
+
+			reqBody := newBodyBuffer(cfg.MaxBodySize)
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeReadCloser{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+			}
+
+			rw := &responseWriter{ResponseWriter: w, body: newBodyBuffer(cfg.MaxBodySize)}
+
+			// the audit line of a handler panicking has the status 500, the panic goes on
+			defer func() {
+				status, rec := rw.statusCode(), recover()
+				if rec != nil {
+					status = http.StatusInternalServerError
+				}
+
+				fields := AuditAPIFields(r.Method, cfg.endpoint(r.URL).RequestURI(), cfg.body(reqBody),
+					cfg.body(rw.body), status)
+				fields[LatencyKey] = time.Since(start).String()
+				_, fields[BytesKey], _ = rw.body.contents()
+
+				entry := tl.WithFields(fields)
+				if rec != nil {
+					entry = entry.WithError(fmt.Errorf("panic: %v", rec))
+				}
+
+				entry.Log(logrusLevels[cfg.Level], "audit api")
+
+				if rec != nil {
+					panic(rec)
+				}
+			}()
+
+			ctx := ContextWithTrace(ContextWithRequestID(IntoContext(r.Context(), tl), id), trace)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+// withDefaults returns the config with the defaults set
+func (c HTTPConfig) withDefaults() HTTPConfig {
+	if c.User == nil {
+		c.User = basicAuthUser
+	}
+
+	if c.RequestIDHeader == "" {
+		c.RequestIDHeader = RequestIDHeader
+	}
+
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = DefaultMaxBodySize
+	}
+
+	if c.Redactor == nil {
+		c.Redactor = redact.Default()
+	}
+
+	if lvl, err := ParseLevel(string(c.Level)); err == nil {
+		c.Level = lvl
+	} else {
+		c.Level = DebugLevel
+	}
+
+	return c
+}
+
+// body returns the redacted body, truncated when over the size limit
+func (c HTTPConfig) body(b *bodyBuffer) string {
+	body, size, truncated := b.contents()
+	if b.max < 0 || size == 0 {
+		return ""
+	}
+
+	if truncated {
+		return c.Redactor.String(body) + truncatedSuffix
+	}
+
+	return c.Redactor.Text(body)
+}
+
+// endpoint returns a copy of the url with the query redacted: the values of the parameters matching a field
+// rule are masked, the other values get the regex rules
+func (c HTTPConfig) endpoint(u *url.URL) *url.URL {
+	redacted := *u
+	if u.RawQuery == "" {
+		return &redacted
+	}
+
+	params := strings.Split(u.RawQuery, "&")
+
+	for i, param := range params {
+		j := strings.Index(param, "=")
+		if j < 0 {
+			continue
+		}
+
+		if name, err := url.QueryUnescape(param[:j]); err == nil && c.Redactor.MatchField(name) {
+			params[i] = param[:j+1] + redact.Mask
+		} else {
+			params[i] = param[:j+1] + c.Redactor.String(param[j+1:])
+		}
+	}
+
+	redacted.RawQuery = strings.Join(params, "&")
+
+	return &redacted
+}
+
+// AuditAPIFields fields of the api audit line (LogAuditAPI, Middleware, AuditTransport)
+func AuditAPIFields(httpType, endpoint, request, response string, responseCode int) logrus.Fields {
+	return logrus.Fields{
+		"httpType":     httpType,
+		"endpoint":     endpoint,
+		"request":      request,
+		"response":     response,
+		"responseCode": responseCode,
+		"auditType":    "api",
+	}
+}
+
+// httpOperation maps the http method to the operation
+func httpOperation(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return logconstants.Read
+	case http.MethodPost:
+		return logconstants.Create
+	case http.MethodPut, http.MethodPatch:
+		return logconstants.Update
+	case http.MethodDelete:
+		return logconstants.Delete
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+func basicAuthUser(r *http.Request) string {
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// NewRequestID returns a random 128 bits hex id
+func NewRequestID() string {
+	return randomHex(16)
+}
+
+// bodyBuffer keeps the first max bytes written and counts all of them, the request body of a client
+// can be written by the transport while the response is read
+type bodyBuffer struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	max  int
+	size int
+}
+
+func newBodyBuffer(max int) *bodyBuffer {
+	return &bodyBuffer{max: max}
+}
+
+// Write keeps the bytes up to the limit
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if keep := b.max - b.buf.Len(); keep > 0 {
+		if keep > len(p) {
+			keep = len(p)
+		}
+
+		b.buf.Write(p[:keep])
+	}
+
+	b.size += len(p)
+
+	return len(p), nil
+}
+
+// contents returns the bytes kept, the number of bytes written and whether bytes were not kept
+func (b *bodyBuffer) contents() (string, int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String(), b.size, b.size > b.buf.Len()
+}
+
+// teeReadCloser body read through a tee
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseWriter keeps the status code and the body written
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	body   *bodyBuffer
+}
+
+// WriteHeader keeps the status code
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write keeps the body
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	_, _ = w.body.Write(p[:n])
+
+	return n, err
+}
+
+// Flush flushes the response when supported
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection when supported (websocket upgrades), the status is 101 when none was written
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// Push pushes the target when supported (http/2)
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped response writer (http.ResponseController)
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
@@ -0,0 +1,215 @@
+package log
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel).SetCluster("minikube")
+
+	handler := Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		FromContext(r.Context()).WithField("body", string(body)).Info("handled")
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"iphone","token":"t1"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?dry=true&access_token=t1", strings.NewReader(`{"password":"secret1"}`))
+	req.SetBasicAuth("johnny", "secret1")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	id := rec.Header().Get(RequestIDHeader)
+	assert.Len(t, id, 32)
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 2) {
+		for _, f := range fields {
+			assert.Equal(t, "minikube", f["cluster"])
+			assert.Equal(t, "johnny", f["user"])
+			assert.Equal(t, "create", f["operation"])
+			assert.Equal(t, id, f[RequestIDKey])
+		}
+
+		assert.Equal(t, "handled", fields[0]["msg"])
+		assert.Equal(t, `{"password":"secret1"}`, fields[0]["body"])
+
+		assert.Equal(t, "audit api", fields[1]["msg"])
+		assert.Equal(t, "debug", fields[1]["level"])
+		assert.Equal(t, "api", fields[1]["auditType"])
+		assert.Equal(t, "POST", fields[1]["httpType"])
+		assert.Equal(t, "/orders?dry=true&access_token=[REDACTED]", fields[1]["endpoint"])
+		assert.Equal(t, `{"password":"[REDACTED]"}`, fields[1]["request"])
+		assert.Equal(t, `{"name":"iphone","token":"[REDACTED]"}`, fields[1]["response"])
+		assert.Equal(t, float64(201), fields[1]["responseCode"])
+		assert.Equal(t, float64(30), fields[1][BytesKey])
+		assert.NotEmpty(t, fields[1][LatencyKey])
+	}
+
+	// the logger is unchanged
+	assert.NotContains(t, l.Data, RequestIDKey)
+}
+
+func TestMiddlewareConfig(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType)
+
+	r, err := redact.New(redact.Config{Fields: []string{"name"}, NoDefaults: true})
+	assert.NoError(t, err)
+
+	cfg := HTTPConfig{
+		User:            func(r *http.Request) string { return r.Header.Get("X-User") },
+		RequestIDHeader: "X-Correlation-Id",
+		MaxBodySize:     20,
+		Redactor:        r,
+		Level:           InfoLevel,
+	}
+
+	handler := Middleware(l, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"iphone"}`))
+	}))
+
+	tests := []struct {
+		method    string
+		operation string
+		user      interface{}
+		response  string
+	}{
+		{http.MethodGet, "read", "u1", `{"name":"[REDACTED]"}`},
+		{http.MethodPut, "update", nil, `{"name":"[REDACTED]"}`},
+		{http.MethodPatch, "update", nil, `{"name":"[REDACTED]"}`},
+		{http.MethodDelete, "delete", nil, `{"name":"[REDACTED]"}`},
+		{http.MethodOptions, "options", nil, `{"name":"[REDACTED]"}`},
+	}
+
+	for _, tt := range tests {
+		out.Reset()
+
+		req := httptest.NewRequest(tt.method, "/orders/1", nil)
+		req.Header.Set("X-Correlation-Id", "c1")
+
+		if tt.user != nil {
+			req.Header.Set("X-User", tt.user.(string))
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "c1", rec.Header().Get("X-Correlation-Id"))
+
+		fields := jsonLines(out)
+		if assert.Len(t, fields, 1, tt.method) {
+			assert.Equal(t, "info", fields[0]["level"])
+			assert.Equal(t, tt.operation, fields[0]["operation"])
+			assert.Equal(t, tt.user, fields[0]["user"])
+			assert.Equal(t, "c1", fields[0][RequestIDKey])
+			assert.Equal(t, "", fields[0]["request"])
+			assert.Equal(t, tt.response, fields[0]["response"])
+			assert.Equal(t, float64(200), fields[0]["responseCode"])
+			assert.Equal(t, float64(17), fields[0][BytesKey])
+		}
+	}
+
+	// truncated and no bodies
+	for size, response := range map[int]string{5: "404 p...(truncated)", -1: ""} {
+		out.Reset()
+		cfg.MaxBodySize = size
+		Middleware(l, cfg)(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		fields := jsonLines(out)
+		if assert.Len(t, fields, 1) {
+			assert.Equal(t, response, fields[0]["response"])
+			assert.Equal(t, float64(404), fields[0]["responseCode"])
+			assert.Equal(t, float64(19), fields[0][BytesKey])
+		}
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel)
+
+	served := make(chan struct{})
+
+	handler := Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.ErrNotSupported, w.(http.Pusher).Push("/app.js", nil))
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+	}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		close(served)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	if resp, err := http.DefaultClient.Do(req); assert.NoError(t, err) {
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.NoError(t, resp.Body.Close())
+	}
+
+	<-served
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "/ws", fields[0]["endpoint"])
+		assert.Equal(t, float64(101), fields[0]["responseCode"])
+	}
+
+	// not supported by the recorder
+	Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := w.(http.Hijacker).Hijack()
+		assert.Equal(t, http.ErrNotSupported, err)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws", nil))
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel)
+
+	handler := Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil order")
+	}))
+
+	assert.PanicsWithValue(t, "nil order", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	})
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "audit api", fields[0]["msg"])
+		assert.Equal(t, float64(500), fields[0]["responseCode"])
+		assert.Equal(t, "panic: nil order", fields[0]["error"])
+	}
+}
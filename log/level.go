@@ -0,0 +1,69 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var logrusLevels = map[LevelLog]logrus.Level{
+	TraceLevel: logrus.TraceLevel,
+	DebugLevel: logrus.DebugLevel,
+	InfoLevel:  logrus.InfoLevel,
+	WarnLevel:  logrus.WarnLevel,
+	ErrorLevel: logrus.ErrorLevel,
+	FatalLevel: logrus.FatalLevel,
+	PanicLevel: logrus.PanicLevel,
+}
+
+// ParseLevel parses a level name (case insensitive, "warning" is accepted for warn)
+func ParseLevel(level string) (LevelLog, error) {
+	lvl := LevelLog(strings.ToLower(strings.TrimSpace(level)))
+	if lvl == "warning" {
+		lvl = WarnLevel
+	}
+
+	if _, ok := logrusLevels[lvl]; !ok {
+		return "", fmt.Errorf("invalid log level %q", level)
+	}
+
+	return lvl, nil
+}
+
+func toLogrusLevel(level LevelLog) (logrus.Level, error) {
+	lvl, err := ParseLevel(string(level))
+	if err != nil {
+		return logrus.PanicLevel, err
+	}
+
+	return logrusLevels[lvl], nil
+}
+
+func fromLogrusLevel(level logrus.Level) LevelLog {
+	for lvl, l := range logrusLevels {
+		if l == level {
+			return lvl
+		}
+	}
+
+	return PanicLevel
+}
+
+// syslogSeverity maps the level to the syslog severity (RFC 5424), used by GELF and syslog
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1 // alert
+	case logrus.FatalLevel:
+		return 2 // critical
+	case logrus.ErrorLevel:
+		return 3 // error
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		want    LevelLog
+		wantErr bool
+	}{
+		{name: "trace", level: "trace", want: TraceLevel},
+		{name: "debug", level: "debug", want: DebugLevel},
+		{name: "info upper case", level: "INFO", want: InfoLevel},
+		{name: "warn", level: "warn", want: WarnLevel},
+		{name: "warning", level: "warning", want: WarnLevel},
+		{name: "error", level: "error", want: ErrorLevel},
+		{name: "fatal", level: "fatal", want: FatalLevel},
+		{name: "panic", level: "panic", want: PanicLevel},
+		{name: "unknown", level: "verbose", wantErr: true},
+		{name: "empty", level: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAllLevels(t *testing.T) {
+	logger := newLogger()
+
+	for _, level := range []LevelLog{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel, PanicLevel} {
+		logger.SetLevel(level)
+		assert.Equal(t, level, logger.GetLevel())
+	}
+}
+
+func TestSetLevelInvalid(t *testing.T) {
+	logger := newLogger()
+	logger.SetLevel(WarnLevel)
+	logger.SetLevel("verbose")
+	assert.Equal(t, WarnLevel, logger.GetLevel())
+}
+
+func TestIndependentLevels(t *testing.T) {
+	globalLevel := logrus.GetLevel()
+
+	log1 := NewLogger().SetLevel(DebugLevel)
+	log2 := NewLogger().SetLevel(ErrorLevel)
+
+	assert.Equal(t, DebugLevel, log1.GetLevel())
+	assert.Equal(t, ErrorLevel, log2.GetLevel())
+	assert.Equal(t, globalLevel, logrus.GetLevel())
+
+	logAndAssertJSON(t, log1, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "test", fields["msg"])
+	})
+
+	logAndAssertJSON(t, log2, "test", func(fields logrus.Fields) {
+		assert.Nil(t, fields["msg"])
+	})
+}
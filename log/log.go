@@ -2,20 +2,51 @@
 package log
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/arutselvan15/go-utils/diff"
+	"github.com/arutselvan15/go-utils/redact"
 	"github.com/golang-collections/collections/stack"
 	"github.com/mattn/go-colorable"
 	"github.com/sirupsen/logrus"
-	"github.com/snowzach/rotatefilehook"
 )
 
 // RFC3339NanoFixed is time.RFC3339Nano with nanoseconds padded using zeros to
 // ensure the formatted time is always the same number of characters.
 const RFC3339NanoFixed = "2006-01-02T15:04:05.000000000Z07:00"
 
+// context field keys
+const (
+	// ClusterKey ClusterKey
+	ClusterKey = "cluster"
+	// ApplicationKey ApplicationKey
+	ApplicationKey = "app"
+	// ResourceKey ResourceKey
+	ResourceKey = "resource"
+	// ComponentKey ComponentKey
+	ComponentKey = "component"
+	// OperationKey OperationKey
+	OperationKey = "operation"
+	// ObjectNameKey ObjectNameKey
+	ObjectNameKey = "objectName"
+	// ObjectStateKey ObjectStateKey
+	ObjectStateKey = "objectState"
+	// UserKey UserKey
+	UserKey = "user"
+	// StepKey StepKey
+	StepKey = "step"
+	// StepStateKey StepStateKey
+	StepStateKey = "stepState"
+)
+
+// contextKeys context field keys in display order
+var contextKeys = []string{
+	ClusterKey, ApplicationKey, ResourceKey, ComponentKey, OperationKey,
+	ObjectNameKey, ObjectStateKey, UserKey, StepKey, StepStateKey,
+}
+
 // FormatterType formatter type
 type FormatterType string
 
@@ -27,10 +58,24 @@ var (
 	TextFormatterType FormatterType = "text"
 	// JSONFormatterType JSONFormatterType
 	JSONFormatterType FormatterType = "json"
+	// LogfmtFormatterType LogfmtFormatterType
+	LogfmtFormatterType FormatterType = "logfmt"
+	// ECSFormatterType ECSFormatterType
+	ECSFormatterType FormatterType = "ecs"
+	// GELFFormatterType GELFFormatterType
+	GELFFormatterType FormatterType = "gelf"
+	// TraceLevel TraceLevel
+	TraceLevel LevelLog = "trace"
 	// DebugLevel DebugLevel
 	DebugLevel LevelLog = "debug"
 	// InfoLevel InfoLevel
 	InfoLevel LevelLog = "info"
+	// WarnLevel WarnLevel
+	WarnLevel LevelLog = "warn"
+	// ErrorLevel ErrorLevel
+	ErrorLevel LevelLog = "error"
+	// FatalLevel FatalLevel
+	FatalLevel LevelLog = "fatal"
 	// PanicLevel PanicLevel
 	PanicLevel LevelLog = "panic"
 )
@@ -41,6 +86,10 @@ type CommonLog interface {
 	GetEntry() *logrus.Entry
 	GetLogger() *Log
 	SetLevel(LevelLog) *Log
+	GetLevel() LevelLog
+	SetFieldLevel(key, value string, level LevelLog) *Log
+	RemoveFieldLevel(key, value string) *Log
+	GetFieldLevels() []LevelRule
 	SetCluster(string) *Log
 	SetApplication(string) *Log
 	SetResource(string) *Log
@@ -51,11 +100,37 @@ type CommonLog interface {
 	SetUser(user string) *Log
 	SetStep(string) *Log
 	SetStepState(string) *Log
+	SetField(key string, value interface{}) *Log
+	RemoveField(key string) *Log
+	With(key string, value interface{}) *Log
+	WithCluster(string) *Log
+	WithApplication(string) *Log
+	WithResource(string) *Log
+	WithComponent(string) *Log
+	WithOperation(string) *Log
+	WithObjectName(string) *Log
+	WithObjectState(string) *Log
+	WithUser(string) *Log
+	WithStep(string) *Log
+	WithStepState(string) *Log
+	SetTrace(TraceContext) *Log
+	WithTrace(TraceContext) *Log
 	LogAuditAPI(string, string, string, string, int)
 	LogAuditObject(...interface{})
 	LogAuditEvent(string)
 	SetFormatterType(fType FormatterType) *Log
 	SetLogFileFormatterType(fType FormatterType) *Log
+	Rotate() error
+	AddSink(sink Sink) error
+	RemoveSink(name string) error
+	GetSinks() []string
+	EnableAsync(cfg AsyncConfig) error
+	Flush(ctx context.Context) error
+	Close() error
+	GetAsyncStats() AsyncStats
+	EnableSampling(cfg SamplingConfig) error
+	DisableSampling()
+	SetRedactor(r *redact.Redactor) *Log
 	PushContext()
 	PopContext()
 	SaveContext()
@@ -65,200 +140,267 @@ type CommonLog interface {
 }
 
 // Log log
+// The logging methods (Info, WithField, ...) and the setters can be used in parallel, the setters change
+// the context seen by all the goroutines using it; With* child loggers leave it unchanged.
 type Log struct {
 	*logrus.Entry
-	logger           *logrus.Logger
-	logLevel         logrus.Level
-	cluster          string
-	application      string
-	resource         string
-	component        string
-	operation        string
-	objectName       string
-	objectState      string
-	user             string
-	step             string
-	stepState        string
-	logFile          string
-	logFileMaxSize   int
-	logFileMaxAge    int
-	logFileMaxBackup int
+	logger   *logrus.Logger
+	pipeline *pipeline
+
+	// mu guards the entry, the fields and the stacks of the context
+	mu sync.RWMutex
+
+	// context fields (cluster, app, ..., SetField), replaced on every change so snapshots can share them
+	fields logrus.Fields
 
 	// used for push/pop of contexts
-	contextStack *stack.Stack
+	contextStack *syncStack
 
 	// save/restore context stack
-	savedContexts *stack.Stack
+	savedContexts *syncStack
+}
+
+// contextSnapshot fields of a context pushed with PushContext
+type contextSnapshot struct {
+	fields logrus.Fields
+	entry  *logrus.Entry
+}
+
+// savedContext context and push/pop stack saved with SaveContext
+type savedContext struct {
+	contextSnapshot
+	contextStack *syncStack
 }
 
 // GetEntry get entry
 func (l *Log) GetEntry() *logrus.Entry {
-	return l.Entry
+	return l.entry()
 }
 
 // GetLogger creates and returns a new logging context.
-// A logging context is a wrapper for a log entry for a logger.  These objects can NOT be used
-// in parallel.
+// A logging context is a wrapper for a log entry for a logger, it shares the push/pop and save/restore
+// stacks of l.
 // GetLogger GetLogger
 func (l *Log) GetLogger() *Log {
-	nl := newLog(l.logger, l.contextStack, l.savedContexts)
-	return nl
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.newContext(l.contextStack, l.savedContexts)
 }
 
 // SetLevel sets the level at which log messages are published/written.
+// Only the logger of this context (shared with GetLogger/ThreadLogger) is changed, an unknown
+// level is reported as a warning and leaves the current level unchanged.
 func (l *Log) SetLevel(level LevelLog) *Log {
-	loglevel, err := logrus.ParseLevel(string(level))
+	loglevel, err := toLogrusLevel(level)
 	if err != nil {
-		// set default level on error
-		loglevel, _ = logrus.ParseLevel(string(DebugLevel))
+		l.WithError(err).Warn("set level ignored")
+		return l
 	}
 
-	logrus.SetLevel(loglevel)
-	l.Logger.SetLevel(loglevel)
-	l.logLevel = loglevel
+	l.pipeline.setLevel(loglevel)
 
 	return l
 }
 
-// SetCluster adds cluster name
-func (l *Log) SetCluster(cluster string) *Log {
-	if cluster == "" {
-		delete(l.Data, "cluster")
-		l.cluster = ""
-	} else {
-		l.cluster = cluster
-		l.Entry = l.WithField("cluster", l.cluster)
+// GetLevel returns the level at which log messages are published/written.
+func (l *Log) GetLevel() LevelLog {
+	return fromLogrusLevel(l.pipeline.getLevel())
+}
+
+// SetFieldLevel sets the level used for the entries whose field key has the value (e.g. component=webhook).
+// Rules are evaluated at emit time in the order they were added and the first match wins, entries matching
+// no rule use the level set with SetLevel. An unknown level is reported as a warning and ignored.
+func (l *Log) SetFieldLevel(key, value string, level LevelLog) *Log {
+	lvl, err := ParseLevel(string(level))
+	if err != nil {
+		l.WithError(err).Warn("set field level ignored")
+		return l
 	}
 
+	l.pipeline.setRule(LevelRule{Key: key, Value: value, Level: lvl})
+
+	return l
+}
+
+// RemoveFieldLevel removes the level set with SetFieldLevel for the field key and value
+func (l *Log) RemoveFieldLevel(key, value string) *Log {
+	l.pipeline.removeRule(key, value)
 	return l
 }
 
+// GetFieldLevels returns the levels set with SetFieldLevel in evaluation order
+func (l *Log) GetFieldLevels() []LevelRule {
+	return l.pipeline.getRules()
+}
+
+// SetCluster adds cluster name
+func (l *Log) SetCluster(cluster string) *Log {
+	return l.setStringField(ClusterKey, cluster)
+}
+
 // SetApplication adds the app name
 func (l *Log) SetApplication(app string) *Log {
-	if app == "" {
-		delete(l.Data, "app")
-		l.application = ""
-	} else {
-		l.application = app
-		l.Entry = l.WithField("app", l.application)
-	}
-
-	return l
+	return l.setStringField(ApplicationKey, app)
 }
 
 // SetResource adds the resource
 func (l *Log) SetResource(resource string) *Log {
-	if resource == "" {
-		delete(l.Data, "resource")
-		l.resource = ""
-	} else {
-		l.resource = resource
-		l.Entry = l.WithField("resource", l.resource)
-	}
-
-	return l
+	return l.setStringField(ResourceKey, resource)
 }
 
 // SetComponent adds the component (service, validator, controller)
 func (l *Log) SetComponent(component string) *Log {
-	if component == "" {
-		delete(l.Data, "component")
-		l.component = ""
-	} else {
-		l.component = component
-		l.Entry = l.WithField("component", l.component)
-	}
-
-	return l
+	return l.setStringField(ComponentKey, component)
 }
 
 // SetOperation adds the operation create/update/delete
 func (l *Log) SetOperation(operation string) *Log {
-	if operation == "" {
-		delete(l.Data, "operation")
-		l.operation = ""
-	} else {
-		l.operation = operation
-		l.Entry = l.WithField("operation", l.operation)
-	}
-
-	return l
+	return l.setStringField(OperationKey, operation)
 }
 
 // SetObjectName adds the object name
 func (l *Log) SetObjectName(objectName string) *Log {
-	if objectName == "" {
-		delete(l.Data, "objectName")
-		l.objectName = ""
-	} else {
-		l.objectName = objectName
-		l.Entry = l.WithField("objectName", l.objectName)
-	}
-
-	return l
+	return l.setStringField(ObjectNameKey, objectName)
 }
 
 // SetObjectState adds the state
 func (l *Log) SetObjectState(state string) *Log {
-	if state == "" {
-		delete(l.Data, "objectState")
-		l.objectState = ""
-	} else {
-		l.objectState = state
-		l.Entry = l.WithField("objectState", l.objectState)
-	}
-
-	return l
+	return l.setStringField(ObjectStateKey, state)
 }
 
 // SetUser adds the user
 func (l *Log) SetUser(user string) *Log {
-	if user == "" {
-		delete(l.Data, "user")
-		l.user = ""
-	} else {
-		l.user = user
-		l.Entry = l.WithField("user", l.user)
-	}
-
-	return l
+	return l.setStringField(UserKey, user)
 }
 
 // SetStep adds the step (step1, step2)
 func (l *Log) SetStep(step string) *Log {
-	if step == "" {
-		delete(l.Data, "step")
-		l.step = ""
-	} else {
-		l.step = step
-		l.Entry = l.WithField("step", l.step)
+	return l.setStringField(StepKey, step)
+}
+
+// SetStepState adds the phase
+func (l *Log) SetStepState(state string) *Log {
+	return l.setStringField(StepStateKey, state)
+}
+
+// SetField adds the field to the context, it is kept by PushContext/PopContext, SaveContext/RestoreContext
+// and ThreadLogger like the other context fields
+func (l *Log) SetField(key string, value interface{}) *Log {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fields := make(logrus.Fields, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
 	}
 
+	fields[key] = value
+
+	l.fields = fields
+	l.Entry = l.Entry.WithField(key, value)
+
 	return l
 }
 
-// SetStepState adds the phase
-func (l *Log) SetStepState(state string) *Log {
-	if state == "" {
-		delete(l.Data, "stepState")
-		l.stepState = ""
-	} else {
-		l.stepState = state
-		l.Entry = l.WithField("stepState", l.stepState)
+// RemoveField removes the field from the context
+func (l *Log) RemoveField(key string) *Log {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.fields[key]; !ok {
+		if _, ok := l.Data[key]; !ok {
+			return l
+		}
 	}
 
+	fields := make(logrus.Fields, len(l.fields))
+	for k, v := range l.fields {
+		if k != key {
+			fields[k] = v
+		}
+	}
+
+	data := make(logrus.Fields, len(l.Data))
+	for k, v := range l.Data {
+		if k != key {
+			data[k] = v
+		}
+	}
+
+	l.fields = fields
+	l.Entry = l.logger.WithFields(data)
+
 	return l
 }
 
+// setStringField sets the field, an empty value removes it
+func (l *Log) setStringField(key, value string) *Log {
+	if value == "" {
+		return l.RemoveField(key)
+	}
+
+	return l.SetField(key, value)
+}
+
+// With returns a child logger (see ThreadLogger) with the field added, l is left unchanged
+func (l *Log) With(key string, value interface{}) *Log {
+	return l.ThreadLogger().SetField(key, value)
+}
+
+// WithCluster returns a child logger with the cluster name
+func (l *Log) WithCluster(cluster string) *Log {
+	return l.ThreadLogger().SetCluster(cluster)
+}
+
+// WithApplication returns a child logger with the app name
+func (l *Log) WithApplication(app string) *Log {
+	return l.ThreadLogger().SetApplication(app)
+}
+
+// WithResource returns a child logger with the resource
+func (l *Log) WithResource(resource string) *Log {
+	return l.ThreadLogger().SetResource(resource)
+}
+
+// WithComponent returns a child logger with the component
+func (l *Log) WithComponent(component string) *Log {
+	return l.ThreadLogger().SetComponent(component)
+}
+
+// WithOperation returns a child logger with the operation
+func (l *Log) WithOperation(operation string) *Log {
+	return l.ThreadLogger().SetOperation(operation)
+}
+
+// WithObjectName returns a child logger with the object name
+func (l *Log) WithObjectName(objectName string) *Log {
+	return l.ThreadLogger().SetObjectName(objectName)
+}
+
+// WithObjectState returns a child logger with the object state
+func (l *Log) WithObjectState(state string) *Log {
+	return l.ThreadLogger().SetObjectState(state)
+}
+
+// WithUser returns a child logger with the user
+func (l *Log) WithUser(user string) *Log {
+	return l.ThreadLogger().SetUser(user)
+}
+
+// WithStep returns a child logger with the step
+func (l *Log) WithStep(step string) *Log {
+	return l.ThreadLogger().SetStep(step)
+}
+
+// WithStepState returns a child logger with the step state
+func (l *Log) WithStepState(state string) *Log {
+	return l.ThreadLogger().SetStepState(state)
+}
+
 // LogAuditAPI log api request and response with fields
 func (l *Log) LogAuditAPI(httpType, endpoint, request, response string, responseCode int) {
-	l.WithField("httpType", httpType).WithField("endpoint", endpoint).WithField(
-		"request", request).WithField("responseCode", responseCode).WithField(
-		"response", response).WithField("auditType", "api").Debug("audit api")
-
-	for _, i := range []string{"auditType", "httpType", "endpoint", "request", "responseCode", "response"} {
-		delete(l.Data, i)
-	}
+	l.WithFields(AuditAPIFields(httpType, endpoint, request, response, responseCode)).Debug("audit api")
 }
 
 // LogAuditObject log object and object diffs
@@ -271,7 +413,7 @@ func (l *Log) LogAuditObject(objects ...interface{}) {
 	)
 
 	if len(objects) > 0 {
-		tmp, err := json.Marshal(objects[0])
+		tmp, err := redact.Marshal(objects[0])
 		if err != nil {
 			oldObject = objects[0]
 		} else {
@@ -282,7 +424,7 @@ func (l *Log) LogAuditObject(objects ...interface{}) {
 	}
 
 	if len(objects) > val1 {
-		tmp, err := json.Marshal(objects[1])
+		tmp, err := redact.Marshal(objects[1])
 		if err != nil {
 			newObject = objects[1]
 		} else {
@@ -291,7 +433,13 @@ func (l *Log) LogAuditObject(objects ...interface{}) {
 
 		ch, _ := diff.GetDiffChangelog(objects[0], objects[1])
 		if ch != nil {
+			redactor := l.pipeline.getRedactor()
+
 			for _, c := range *ch {
+				if redactor != nil && redactedChange(redactor, c.Path) {
+					c.From, c.To = redact.Mask, redact.Mask
+				}
+
 				objDiff = objDiff + fmt.Sprintf("(%v, %v, %v, %v)\n", c.Path, c.Type, c.From, c.To)
 			}
 		}
@@ -302,33 +450,36 @@ func (l *Log) LogAuditObject(objects ...interface{}) {
 
 	l.WithField("oldObject", oldObject).WithField("newObject", newObject).WithField(
 		"objectDiff", objDiff).WithField("auditType", "object").Debug("audit object")
-
-	for _, i := range []string{"auditType", "oldObject", "newObject", "objectDiff"} {
-		delete(l.Data, i)
-	}
 }
 
 // LogAuditEvent log events
 func (l *Log) LogAuditEvent(message string) {
 	l.WithField("auditType", "event").Debug(message)
-	delete(l.Data, "auditType")
 }
 
 // SetFormatterType set format
 func (l *Log) SetFormatterType(fType FormatterType) *Log {
 	if fType == JSONFormatterType {
-		l.logger.SetFormatter(&logrus.JSONFormatter{
+		l.pipeline.setFormatter(&logrus.JSONFormatter{
+			TimestampFormat: RFC3339NanoFixed,
+		})
+	} else if fType == ECSFormatterType {
+		l.pipeline.setFormatter(&ECSFormatter{})
+	} else if fType == GELFFormatterType {
+		l.pipeline.setFormatter(&GELFFormatter{})
+	} else if fType == LogfmtFormatterType {
+		l.pipeline.setFormatter(&LogfmtFormatter{
 			TimestampFormat: RFC3339NanoFixed,
 		})
 	} else if fType == TextFormatterType {
-		l.logger.SetFormatter(&logrus.TextFormatter{
+		l.pipeline.setFormatter(&logrus.TextFormatter{
 			ForceColors:      true,
 			FullTimestamp:    true,
 			QuoteEmptyFields: true,
 			TimestampFormat:  RFC3339NanoFixed,
 		})
 	} else {
-		l.logger.SetFormatter(&logrus.TextFormatter{
+		l.pipeline.setFormatter(&logrus.TextFormatter{
 			ForceColors:      true,
 			FullTimestamp:    true,
 			QuoteEmptyFields: true,
@@ -339,34 +490,173 @@ func (l *Log) SetFormatterType(fType FormatterType) *Log {
 	return l
 }
 
-// SetLogFileFormatterType set file format
+func isFormatterType(fType FormatterType) bool {
+	return fType == TextFormatterType || fType == JSONFormatterType || fType == LogfmtFormatterType ||
+		fType == ECSFormatterType || fType == GELFFormatterType
+}
+
+// SetLogFileFormatterType set file format, the formatter of the log file sink is replaced
 func (l *Log) SetLogFileFormatterType(fType FormatterType) *Log {
-	if l.logFile != "" {
-		rotateFileHook, err := rotatefilehook.NewRotateFileHook(getRotateConfig(l, fType))
-		if err == nil {
-			l.logger.AddHook(rotateFileHook)
+	l.pipeline.setSinkFormat(FileSinkName, fType)
+	return l
+}
+
+// Rotate closes the log files of the file sinks and starts new ones, e.g. from a signal handler after
+// an external rotation. Does nothing without file sink.
+func (l *Log) Rotate() error {
+	for _, s := range l.pipeline.getSinks() {
+		if w, ok := s.Writer.(*rotatingWriter); ok {
+			if err := w.Rotate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// AddSink adds a named output to the logger (and the contexts sharing it), an error is returned when
+// the sink is invalid or its name is already used
+func (l *Log) AddSink(sink Sink) error {
+	s, err := newSink(sink)
+	if err != nil {
+		return err
+	}
+
+	return l.pipeline.addSink(s)
+}
+
+// RemoveSink removes the named output from the logger, the outputs opened by the package (NewFileSink,
+// NewGELFSink, NewSyslogSink) are closed once the buffered entries are written
+func (l *Log) RemoveSink(name string) error {
+	s, ok := l.pipeline.removeSink(name)
+	if !ok {
+		return fmt.Errorf("sink %s not found", name)
+	}
+
+	if async := l.pipeline.getAsync(); async != nil {
+		_ = async.flush(context.Background())
+	}
+
+	return s.close()
+}
+
+// GetSinks returns the names of the sinks
+func (l *Log) GetSinks() []string {
+	var names []string
+
+	for _, s := range l.pipeline.getSinks() {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+// EnableAsync buffers the entries of the logger (and the contexts sharing it) and writes them to the console
+// and the sinks in the background, see AsyncConfig for the policy when the buffer is full. Fatal and panic
+// entries are written right away. The console output must be safe for concurrent use (os.Stdout is).
+func (l *Log) EnableAsync(cfg AsyncConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	if previous := l.pipeline.setAsync(newAsyncQueue(cfg)); previous != nil {
+		previous.close()
+	}
+
+	return nil
+}
+
+// Flush waits for the buffered entries to be written, returns the context error when it is done first
+func (l *Log) Flush(ctx context.Context) error {
+	if async := l.pipeline.getAsync(); async != nil {
+		return async.flush(ctx)
+	}
+
+	return nil
+}
+
+// Close stops the sampling, writes the buffered entries, stops the async mode and closes the outputs opened
+// by the package, files and connections are opened again by the next entries
+func (l *Log) Close() error {
+	l.DisableSampling()
+
+	if async := l.pipeline.setAsync(nil); async != nil {
+		async.close()
+	}
+
+	var firstErr error
+
+	for _, s := range l.pipeline.getSinks() {
+		if err := s.close(); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
 
+	return firstErr
+}
+
+// GetAsyncStats returns the size, usage and drop counters of the async buffer
+func (l *Log) GetAsyncStats() AsyncStats {
+	if async := l.pipeline.getAsync(); async != nil {
+		return async.stats()
+	}
+
+	return AsyncStats{DroppedByLevel: map[LevelLog]uint64{}}
+}
+
+// EnableSampling suppresses the entries of the logger (and the contexts sharing it) over the sampling and
+// rate limits of the config, a summary line per level reports the number of suppressed entries periodically
+func (l *Log) EnableSampling(cfg SamplingConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	s := newSampler(cfg)
+	s.start(l.newContext(newSyncStack(stack.New()), newSyncStack(stack.New())))
+
+	if previous := l.pipeline.setSampler(s); previous != nil {
+		previous.stop()
+	}
+
+	return nil
+}
+
+// DisableSampling stops the sampling, the suppressed entries not reported yet are reported
+func (l *Log) DisableSampling() {
+	if s := l.pipeline.setSampler(nil); s != nil {
+		s.stop()
+	}
+}
+
+// SetRedactor redacts the fields and messages of the logger (and the contexts sharing it) before they are
+// written, nil stops redacting. The fields tagged `redact:"true"` of the audited objects are always redacted.
+func (l *Log) SetRedactor(r *redact.Redactor) *Log {
+	l.pipeline.setRedactor(r)
 	return l
 }
 
 // PushContext PushContext
 func (l *Log) PushContext() {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	// push and pop by value, not by reference
-	l.contextStack.Push(*l)
+	l.contextStack.push(l.snapshot())
 }
 
 // PopContext PopContext
 func (l *Log) PopContext() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	// Do nothing if nothing there
-	if l.contextStack.Len() == 0 {
+	pop, ok := l.contextStack.pop()
+	if !ok {
 		return
 	}
 
-	pop := l.contextStack.Pop().(Log)
-
-	l.copyContextFrom(&pop)
+	l.restore(pop.(contextSnapshot))
 }
 
 // SaveContext SaveContext
@@ -374,19 +664,16 @@ func (l *Log) PopContext() {
 // This saves the current fields and the current stack.
 // After this call, the current context is intact, but has a new empty stack.
 func (l *Log) SaveContext() {
-	// create a new context
-	c := l.GetLogger()
-	// copy state from existing
-	c.copyContextFrom(l)
-	// save the current context stack state
-	c.contextStack = l.contextStack
-	// Push current context for later restore
-	l.savedContexts.Push(c)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Push current context and context stack for later restore
+	l.savedContexts.push(savedContext{contextSnapshot: l.snapshot(), contextStack: l.contextStack})
 
 	// Create new context stack for new scope
 	// Now, current logger is unchanged except for a new push/pop stack, and the current whole context (including
 	// stack) is saved in the saved stack.
-	l.contextStack = stack.New()
+	l.contextStack = newSyncStack(stack.New())
 }
 
 // RestoreContext function
@@ -394,16 +681,20 @@ func (l *Log) SaveContext() {
 // This restores the previouosly saved field and the previous stack.
 // Anything in the stack between Save/Restore is gone.
 func (l *Log) RestoreContext() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	// If no saves, do nothing
-	if l.savedContexts.Len() == 0 {
+	pop, ok := l.savedContexts.pop()
+	if !ok {
 		return
 	}
-	// Get the last saved full context
-	c := l.savedContexts.Pop().(*Log)
+
+	c := pop.(savedContext)
 	// Restore saved push/pop stack
 	l.contextStack = c.contextStack
 	// Restore context data
-	l.copyContextFrom(c)
+	l.restore(c.contextSnapshot)
 }
 
 // PushPop function
@@ -418,12 +709,8 @@ func (l *Log) PushPop(f func()) {
 // This allows the thread to do all context stuff independently of other threads
 // ThreadLogger ThreadLogger
 func (l *Log) ThreadLogger() *Log {
-	// create the context stacks
-	stack1 := stack.New()
-	stack2 := stack.New()
-
-	// create the initial logging context
-	nlog := newLog(l.logger, stack1, stack2)
+	// create the initial logging context with its own context stacks
+	nlog := l.newContext(newSyncStack(stack.New()), newSyncStack(stack.New()))
 	// populate with existing context
 	nlog.copyContextFrom(l)
 
@@ -431,38 +718,40 @@ func (l *Log) ThreadLogger() *Log {
 }
 
 //NewLoggerWithFile log
+// The log file errors are logged, use NewLoggerWithFileE to get them.
+//
+// Deprecated: use NewFromConfig with a FileConfig.
 func NewLoggerWithFile(filename string, maxSize, maxAge, maxBackup int) CommonLog {
-	logger := logrus.New()
-
-	logrus.SetOutput(colorable.NewColorableStdout())
-	logrus.SetFormatter(&logrus.TextFormatter{
-		ForceColors:      true,
-		FullTimestamp:    true,
-		QuoteEmptyFields: true,
-		TimestampFormat:  RFC3339NanoFixed,
-	})
+	l, err := NewLoggerWithFileE(filename, maxSize, maxAge, maxBackup)
+	if err != nil {
+		l = NewLogger()
+		l.WithError(err).Error("file logging disabled")
+	}
 
-	// create the shared context stacks
-	stack1 := stack.New()
-	stack2 := stack.New()
+	return l
+}
 
-	l := newLog(logger, stack1, stack2)
-	l.logFile = filename
-	l.logFileMaxSize = maxSize
-	l.logFileMaxAge = maxAge
-	l.logFileMaxBackup = maxBackup
+// NewLoggerWithFileE creates a logger writing to the rotated log file, returns an error when
+// the log file can not be written
+func NewLoggerWithFileE(filename string, maxSize, maxAge, maxBackup int) (CommonLog, error) {
+	l := newRootLog()
 
-	// log file
-	rotateFileHook, err := rotatefilehook.NewRotateFileHook(getRotateConfig(l, TextFormatterType))
-	if err == nil {
-		l.logger.AddHook(rotateFileHook)
+	err := l.setLogFile(FileConfig{
+		Path: filename, Format: TextFormatterType, MaxSize: maxSize, MaxAge: maxAge, MaxBackup: maxBackup,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return l
+	return l, nil
 }
 
 // NewLogger creates a logger context for a newly created logging output.
 func NewLogger() CommonLog {
+	return newRootLog()
+}
+
+func newRootLog() *Log {
 	logger := logrus.New()
 
 	logrus.SetOutput(colorable.NewColorableStdout())
@@ -481,11 +770,27 @@ func NewLogger() CommonLog {
 	return newLog(logger, stack1, stack2)
 }
 
+// setLogFile adds the log file sink to the logger
+func (l *Log) setLogFile(cfg FileConfig) error {
+	s, err := NewFileSink(FileSinkName, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := l.AddSink(s); err != nil {
+		_ = s.Writer.(*rotatingWriter).Close()
+		return err
+	}
+
+	return nil
+}
+
 func newLog(logger *logrus.Logger, contextStack *stack.Stack, savedContexts *stack.Stack) *Log {
 	nl := &Log{
 		logger:        logger,
-		contextStack:  contextStack,
-		savedContexts: savedContexts,
+		pipeline:      newPipeline(logger),
+		contextStack:  newSyncStack(contextStack),
+		savedContexts: newSyncStack(savedContexts),
 	}
 
 	nl.clear()
@@ -493,56 +798,63 @@ func newLog(logger *logrus.Logger, contextStack *stack.Stack, savedContexts *sta
 	return nl
 }
 
-func getRotateConfig(l *Log, fType FormatterType) rotatefilehook.RotateFileConfig {
-	rc := rotatefilehook.RotateFileConfig{
-		Filename:   l.logFile,
-		MaxSize:    l.logFileMaxSize,
-		MaxBackups: l.logFileMaxBackup,
-		MaxAge:     l.logFileMaxAge,
-		Level:      logrus.DebugLevel,
-		Formatter: &logrus.JSONFormatter{
-			TimestampFormat: RFC3339NanoFixed,
-		},
+// newContext creates an empty logging context sharing the logger of l
+func (l *Log) newContext(contextStack *syncStack, savedContexts *syncStack) *Log {
+	nl := &Log{
+		logger:        l.logger,
+		pipeline:      l.pipeline,
+		contextStack:  contextStack,
+		savedContexts: savedContexts,
 	}
 
-	if fType == TextFormatterType {
-		rc.Formatter = &logrus.TextFormatter{
-			TimestampFormat: RFC3339NanoFixed,
-		}
-	}
+	nl.clear()
 
-	return rc
+	return nl
 }
 
 // Doesn't copy the stack, just the fields
 func (l *Log) copyContextFrom(from *Log) *Log {
-	l.logger = from.logger
-	l.Entry = from.logger.WithFields(logrus.Fields{})
-	l.SetCluster(from.cluster)
-	l.SetApplication(from.application)
-	l.SetResource(from.resource)
-	l.SetComponent(from.component)
-	l.SetOperation(from.operation)
-	l.SetObjectName(from.objectName)
-	l.SetObjectState(from.objectState)
-	l.SetUser(from.user)
-	l.SetStep(from.step)
-	l.SetStepState(from.stepState)
+	from.mu.RLock()
+	snapshot := contextSnapshot{fields: from.fields, entry: from.logger.WithFields(from.fields)}
+	from.mu.RUnlock()
+
+	l.mu.Lock()
+	l.restore(snapshot)
+	l.mu.Unlock()
 
 	return l
 }
 
 func (l *Log) clear() {
-	l.Entry = l.logger.WithFields(logrus.Fields{})
-
-	l.cluster = ""
-	l.application = ""
-	l.resource = ""
-	l.component = ""
-	l.operation = ""
-	l.objectName = ""
-	l.objectState = ""
-	l.user = ""
-	l.step = ""
-	l.stepState = ""
+	l.fields = logrus.Fields{}
+	l.Entry = l.logger.WithFields(l.fields)
+}
+
+// snapshot returns the context fields, the maps are never changed so they are not copied
+func (l *Log) snapshot() contextSnapshot {
+	return contextSnapshot{fields: l.fields, entry: l.Entry}
+}
+
+func (l *Log) restore(c contextSnapshot) {
+	l.fields = c.fields
+	l.Entry = c.entry
+}
+
+// entry returns the entry of the context
+func (l *Log) entry() *logrus.Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.Entry
+}
+
+// redactedChange checks a field of the change path matches the field rules of the redactor
+func redactedChange(r *redact.Redactor, path []string) bool {
+	for _, name := range path {
+		if r.MatchField(name) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -3,7 +3,9 @@ package log
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/arutselvan15/go-utils/logconstants"
@@ -421,6 +423,154 @@ func TestThreadedSaveRestore(t *testing.T) {
 	assertAllFields(t, t2log, "initial")
 }
 
+func TestSetField(t *testing.T) {
+	log := newLogger()
+	log.SetCluster("minikube").SetField("requestId", "r1").SetField("attempt", 1)
+
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "minikube", fields["cluster"])
+		assert.Equal(t, "r1", fields["requestId"])
+		assert.Equal(t, float64(1), fields["attempt"])
+	})
+
+	// push/pop
+	log.PushPop(func() {
+		log.SetField("requestId", "r2").RemoveField("attempt").SetCluster("")
+		logAndAssertJSON(t, log, "test", func(fields logrus.Fields) {
+			assert.Equal(t, "r2", fields["requestId"])
+			assert.NotContains(t, fields, "attempt")
+			assert.NotContains(t, fields, "cluster")
+		})
+	})
+
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "minikube", fields["cluster"])
+		assert.Equal(t, "r1", fields["requestId"])
+		assert.Equal(t, float64(1), fields["attempt"])
+	})
+
+	// save/restore
+	log.SaveContext()
+	log.SetField("requestId", "r3")
+	log.RestoreContext()
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) { assert.Equal(t, "r1", fields["requestId"]) })
+
+	// thread loggers are cloned
+	tlog := log.ThreadLogger()
+	tlog.SetField("requestId", "r4")
+	logAndAssertJSON(t, tlog, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "r4", fields["requestId"])
+		assert.Equal(t, float64(1), fields["attempt"])
+	})
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) { assert.Equal(t, "r1", fields["requestId"]) })
+
+	log.RemoveField("requestId").RemoveField("unknown")
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) { assert.NotContains(t, fields, "requestId") })
+}
+
+func TestWith(t *testing.T) {
+	log := newLogger()
+	log.SetCluster("minikube").SetComponent("webhook")
+
+	child := log.WithComponent("controller").WithOperation("create").WithUser("u1").With("requestId", "r1")
+	logAndAssertJSON(t, child, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "minikube", fields["cluster"])
+		assert.Equal(t, "controller", fields["component"])
+		assert.Equal(t, "create", fields["operation"])
+		assert.Equal(t, "u1", fields["user"])
+		assert.Equal(t, "r1", fields["requestId"])
+	})
+
+	// the parent is unchanged
+	logAndAssertJSON(t, log, "test", func(fields logrus.Fields) {
+		assert.Equal(t, "webhook", fields["component"])
+		assert.NotContains(t, fields, "operation")
+		assert.NotContains(t, fields, "requestId")
+	})
+
+	// an empty value removes the field from the child
+	logAndAssertJSON(t, log.WithCluster(""), "test", func(fields logrus.Fields) {
+		assert.NotContains(t, fields, "cluster")
+	})
+
+	for _, child := range []*Log{
+		log.WithApplication("a"), log.WithResource("a"), log.WithObjectName("a"), log.WithObjectState("a"),
+		log.WithStep("a"), log.WithStepState("a"),
+	} {
+		assert.Len(t, child.fields, 3)
+	}
+
+	assert.Len(t, log.fields, 2)
+}
+
+// TestConcurrentLog run with -race
+func TestConcurrentLog(t *testing.T) {
+	const (
+		goroutines = 16
+		iterations = 200
+	)
+
+	log := newLogger()
+	out := &MemoryWriter{}
+	log.logger.Out = out
+	log.SetFormatterType(JSONFormatterType)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < iterations; j++ {
+				id := fmt.Sprintf("%d-%d", i, j)
+
+				// shared context
+				log.SetField("requestId", id).SetComponent("webhook")
+				log.PushPop(func() {
+					log.SetOperation("create").RemoveField("requestId")
+					log.Info("shared")
+				})
+				log.SaveContext()
+				log.SetUser(id)
+				log.RestoreContext()
+				log.WithField("step", j).Debug("shared")
+
+				// child loggers
+				child := log.WithOperation("update").With("requestId", id)
+				child.PushContext()
+				child.SetStep("step1").Info("child")
+				child.PopContext()
+				log.ThreadLogger().Info("thread")
+
+				if j%50 == 0 {
+					log.SetLevel(InfoLevel)
+					log.SetFieldLevel(ComponentKey, "webhook", InfoLevel)
+					_ = log.GetEntry()
+					_ = log.GetLogger()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	lines := out.Lines()
+	assert.Len(t, lines, goroutines*iterations*3)
+
+	for _, line := range lines {
+		var fields logrus.Fields
+
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+
+		if fields["msg"] == "child" {
+			assert.Equal(t, "update", fields["operation"])
+			assert.Equal(t, "step1", fields["step"])
+		}
+	}
+}
+
 func TestAllLogConstants(t *testing.T) {
 	logger := newLogger()
 
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogfmtFormatter formats entries as logfmt (key=value pairs): time, level and msg first,
+// then the context fields (cluster, app, resource, component, ...) and the other fields sorted
+type LogfmtFormatter struct {
+	// TimestampFormat time format, time.RFC3339 when empty
+	TimestampFormat string
+	// DisableTimestamp no time key
+	DisableTimestamp bool
+}
+
+// Format formats the entry on one line
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	if !f.DisableTimestamp {
+		timestampFormat := f.TimestampFormat
+		if timestampFormat == "" {
+			timestampFormat = time.RFC3339
+		}
+
+		appendLogfmt(b, logrus.FieldKeyTime, entry.Time.Format(timestampFormat))
+	}
+
+	appendLogfmt(b, logrus.FieldKeyLevel, entry.Level.String())
+	appendLogfmt(b, logrus.FieldKeyMsg, entry.Message)
+
+	if entry.HasCaller() {
+		appendLogfmt(b, logrus.FieldKeyFunc, entry.Caller.Function)
+		appendLogfmt(b, logrus.FieldKeyFile, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line))
+	}
+
+	for _, key := range orderedKeys(entry.Data) {
+		appendLogfmt(b, key, entry.Data[key])
+	}
+
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+// orderedKeys returns the context keys in display order followed by the other keys sorted
+func orderedKeys(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	others := make([]string, 0, len(data))
+
+	for _, key := range contextKeys {
+		if _, ok := data[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range data {
+		if !isContextKey(key) {
+			others = append(others, key)
+		}
+	}
+
+	sort.Strings(others)
+
+	return append(keys, others...)
+}
+
+func isContextKey(key string) bool {
+	for _, k := range contextKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendLogfmt(b *bytes.Buffer, key string, value interface{}) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+
+	var s string
+
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if needsQuoting(s) {
+		s = strconv.Quote(s)
+	}
+
+	b.WriteString(s)
+}
+
+// logfmtKey replaces the characters not allowed in a key
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == unicode.ReplacementChar {
+			return '_'
+		}
+
+		return r
+	}, key)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	return false
+}
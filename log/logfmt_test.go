@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		message string
+		fields  logrus.Fields
+		want    string
+	}{
+		{
+			name: "no fields", message: "test",
+			want: `time=2019-08-01T15:04:05Z level=info msg=test`,
+		},
+		{
+			name: "context keys first", message: "process item",
+			fields: logrus.Fields{
+				"zeta": 1, "auditType": "api", StepKey: "process", UserKey: "johnny", ObjectNameKey: "iphone",
+				OperationKey: "create", ComponentKey: "controller", ResourceKey: "product", ApplicationKey: "estore",
+				ClusterKey: "minikube",
+			},
+			want: `time=2019-08-01T15:04:05Z level=info msg="process item" cluster=minikube app=estore ` +
+				`resource=product component=controller operation=create objectName=iphone user=johnny step=process ` +
+				`auditType=api zeta=1`,
+		},
+		{
+			name: "quoting", message: `say "hi"`,
+			fields: logrus.Fields{
+				"empty": "", "space": "a b", "equal": "a=b", "newline": "a\nb", "backslash": `a\b`, "error": errors.New("failed"),
+				"plain": "a-b.c/d:e", "bad key=": "x",
+			},
+			want: `time=2019-08-01T15:04:05Z level=info msg="say \"hi\"" backslash="a\\b" bad_key_=x empty="" ` +
+				`equal="a=b" error=failed newline="a\nb" plain=a-b.c/d:e space="a b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields).WithTime(ts)
+			entry.Level = logrus.InfoLevel
+			entry.Message = tt.message
+
+			got, err := (&LogfmtFormatter{}).Format(entry)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want+"\n", string(got))
+		})
+	}
+}
+
+func TestLogfmtFormatterType(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "logfmt")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+
+	var buffer bytes.Buffer
+
+	logger := NewLoggerWithFile(filename, 1, 1, 1).(*Log)
+	logger.logger.Out = &buffer
+	logger.SetFormatterType(LogfmtFormatterType).SetLogFileFormatterType(LogfmtFormatterType)
+	logger.SetCluster("minikube").SetComponent("webhook").Info("test log")
+
+	for _, line := range []string{buffer.String(), readFile(filename)} {
+		assert.True(t, strings.HasPrefix(line, "time="))
+		assert.Contains(t, line, ` level=info msg="test log" cluster=minikube component=webhook`+"\n")
+	}
+
+	cfg := Config{LevelConfig: LevelConfig{Format: LogfmtFormatterType}, File: FileConfig{Format: LogfmtFormatterType}}
+	assert.Nil(t, cfg.Validate())
+}
+
+func readFile(filename string) string {
+	data, _ := ioutil.ReadFile(filename)
+	return string(data)
+}
@@ -0,0 +1,445 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// LevelRule level used for the entries whose field Key has the value Value
+type LevelRule struct {
+	Key   string
+	Value string
+	Level LevelLog
+}
+
+// pipeline is installed as the formatter of the logrus logger, so every entry passing the
+// logrus level check goes through it exactly once before it is written out to the console,
+// and as a hook writing the entries to the sinks.
+// It is shared by all the contexts created from the same logger (GetLogger/ThreadLogger).
+// In async mode the hook formats the entry for the console and the sinks and buffers it, the
+// formatter then formats it to nothing.
+// The hook marks in the context of each entry whether it is suppressed by the sampler and written by the console
+// (logrus passes the same entry to the formatter), so a change of the settings in between does not drop or
+// duplicate it; the fields and message of the entries not suppressed are redacted by the hook.
+type pipeline struct {
+	mu        sync.RWMutex
+	logger    *logrus.Logger
+	formatter logrus.Formatter
+	level     logrus.Level
+	rules     []LevelRule
+	sinks     []*sink
+	async     *asyncQueue
+	sampler   *sampler
+	redactor  *redact.Redactor
+}
+
+// firedEntry state of an entry captured by the hook for the formatter
+type firedEntry struct {
+	suppressed bool
+	// console the formatter writes the entry, false when suppressed, disabled or buffered by the async queue
+	console bool
+}
+
+func newPipeline(logger *logrus.Logger) *pipeline {
+	p := &pipeline{
+		logger:    logger,
+		formatter: logger.Formatter,
+		level:     logger.GetLevel(),
+	}
+
+	logger.SetFormatter(p)
+	logger.AddHook(p)
+
+	return p
+}
+
+// Format formats the entry with the console formatter, filtered entries are formatted to nothing.
+// The state marked when the hook fired the entry is used, the current settings otherwise.
+func (p *pipeline) Format(entry *logrus.Entry) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if fired, ok := firedState(entry); ok {
+		if !fired.console {
+			return nil, nil
+		}
+	} else if !p.enabled(entry) || (p.async != nil && isAsyncLevel(entry.Level)) {
+		return nil, nil
+	}
+
+	return p.formatter.Format(entry)
+}
+
+// Levels fires the sinks for all levels
+func (p *pipeline) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the entry to the sinks accepting it, the first error is returned after all the sinks are fired
+func (p *pipeline) Fire(entry *logrus.Entry) error {
+	var firstErr error
+
+	p.mu.RLock()
+	sinks := p.sinks
+	enabled := p.enabled(entry)
+	formatter := p.formatter
+	async := p.async
+	sampler := p.sampler
+	redactor := p.redactor
+	p.mu.RUnlock()
+
+	buffered := async != nil && isAsyncLevel(entry.Level)
+
+	if !written(entry, enabled, sinks) {
+		markFired(entry, firedEntry{})
+		return nil
+	}
+
+	if !p.sample(sampler, entry) {
+		markFired(entry, firedEntry{suppressed: true})
+		return nil
+	}
+
+	markFired(entry, firedEntry{console: enabled && !buffered})
+
+	if redactor != nil {
+		// the entry is a copy made by logrus, its data map is shared with the logger
+		entry.Data = redactor.Fields(entry.Data)
+		entry.Message = redactor.String(entry.Message)
+	}
+
+	if async != nil {
+		if buffered {
+			return p.fireAsync(async, entry, formatter, sinks, enabled)
+		}
+
+		// fatal and panic entries are written right away, after the buffered ones
+		_ = async.flush(context.Background())
+	}
+
+	for _, s := range sinks {
+		if !s.accepts(entry, enabled) {
+			continue
+		}
+
+		if err := s.fire(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// fireAsync formats the entry for the console (logrus calls the hooks with the logger locked,
+// so its output can be read here) and the sinks, and buffers it
+func (p *pipeline) fireAsync(async *asyncQueue, entry *logrus.Entry, formatter logrus.Formatter, sinks []*sink,
+	enabled bool) error {
+	var firstErr error
+
+	rec := asyncRecord{level: entry.Level}
+
+	if enabled {
+		if b, err := formatter.Format(entry); err != nil {
+			firstErr = err
+		} else {
+			rec.writes = append(rec.writes, asyncWrite{write: writerFunc(p.logger.Out), data: b})
+		}
+	}
+
+	for _, s := range sinks {
+		if !s.accepts(entry, enabled) {
+			continue
+		}
+
+		if w, ok := s.Writer.(entryWriter); ok {
+			rec.writes = append(rec.writes, asyncWrite{write: s.entryWrite(w.prepareEntry(entry))})
+
+			continue
+		}
+
+		if b, err := s.formatter.Format(entry); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			rec.writes = append(rec.writes, asyncWrite{write: s.write, data: b})
+		}
+	}
+
+	if len(rec.writes) > 0 {
+		async.push(rec)
+	}
+
+	return firstErr
+}
+
+// markFired marks the state of the entry in its context, the entry is a copy made by logrus for the call
+func markFired(entry *logrus.Entry, fired firedEntry) {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	entry.Context = context.WithValue(ctx, firedContextKey, fired)
+}
+
+// firedState returns the state marked by the hook
+func firedState(entry *logrus.Entry) (firedEntry, bool) {
+	if entry.Context == nil {
+		return firedEntry{}, false
+	}
+
+	fired, ok := entry.Context.Value(firedContextKey).(firedEntry)
+
+	return fired, ok
+}
+
+// written checks the entry is written by the console or a sink
+func written(entry *logrus.Entry, enabled bool, sinks []*sink) bool {
+	if enabled {
+		return true
+	}
+
+	for _, s := range sinks {
+		if s.accepts(entry, enabled) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sample checks the entry against the sampler
+func (p *pipeline) sample(sampler *sampler, entry *logrus.Entry) bool {
+	return sampler == nil || sampler.allow(entry)
+}
+
+func (p *pipeline) setRedactor(r *redact.Redactor) {
+	p.mu.Lock()
+	p.redactor = r
+	p.mu.Unlock()
+}
+
+func (p *pipeline) getRedactor() *redact.Redactor {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.redactor
+}
+
+// setSampler replaces the sampler, the previous one is returned
+func (p *pipeline) setSampler(s *sampler) *sampler {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.sampler
+	p.sampler = s
+
+	return previous
+}
+
+// setAsync replaces the async queue, the previous one is returned
+func (p *pipeline) setAsync(async *asyncQueue) *asyncQueue {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.async
+	p.async = async
+
+	return previous
+}
+
+func (p *pipeline) getAsync() *asyncQueue {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.async
+}
+
+func (p *pipeline) setFormatter(formatter logrus.Formatter) {
+	p.mu.Lock()
+	p.formatter = formatter
+	p.mu.Unlock()
+
+	p.logger.SetFormatter(p)
+}
+
+func (p *pipeline) setLevel(level logrus.Level) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.level = level
+	p.updateLoggerLevel()
+}
+
+func (p *pipeline) getLevel() logrus.Level {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.level
+}
+
+func (p *pipeline) setRule(rule LevelRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, r := range p.rules {
+		if r.Key == rule.Key && r.Value == rule.Value {
+			p.rules[i] = rule
+			p.updateLoggerLevel()
+
+			return
+		}
+	}
+
+	p.rules = append(p.rules, rule)
+	p.updateLoggerLevel()
+}
+
+func (p *pipeline) removeRule(key, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, r := range p.rules {
+		if r.Key == key && r.Value == value {
+			p.rules = append(p.rules[:i:i], p.rules[i+1:]...)
+			break
+		}
+	}
+
+	p.updateLoggerLevel()
+}
+
+func (p *pipeline) getRules() []LevelRule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]LevelRule{}, p.rules...)
+}
+
+// addSink adds the sink, sinks are copied on write so Fire can use them without lock
+func (p *pipeline) addSink(s *sink) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, existing := range p.sinks {
+		if existing.Name == s.Name {
+			return fmt.Errorf("sink %s already exists", s.Name)
+		}
+	}
+
+	p.sinks = append(p.sinks[:len(p.sinks):len(p.sinks)], s)
+	p.updateLoggerLevel()
+
+	return nil
+}
+
+func (p *pipeline) removeSink(name string) (*sink, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, s := range p.sinks {
+		if s.Name == name {
+			p.sinks = append(p.sinks[:i:i], p.sinks[i+1:]...)
+			p.updateLoggerLevel()
+
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// setSinkFormat replaces the sink with one using the formatter type
+func (p *pipeline) setSinkFormat(name string, fType FormatterType) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, s := range p.sinks {
+		if s.Name == name {
+			ns := *s
+			ns.Format = fType
+			ns.formatter = formatterOf(ns.Sink)
+
+			sinks := append([]*sink{}, p.sinks...)
+			sinks[i] = &ns
+			p.sinks = sinks
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *pipeline) getSinks() []*sink {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.sinks
+}
+
+// enabled checks the entry level against the first matching rule, or the base level when none matches
+func (p *pipeline) enabled(entry *logrus.Entry) bool {
+	level := p.level
+
+	for _, r := range p.rules {
+		if v, ok := entry.Data[r.Key]; ok && fmt.Sprint(v) == r.Value {
+			level = logrusLevels[r.Level]
+			break
+		}
+	}
+
+	return entry.Level <= level
+}
+
+// updateLoggerLevel lets through the logrus logger everything any rule or sink may need
+func (p *pipeline) updateLoggerLevel() {
+	level := p.level
+
+	for _, r := range p.rules {
+		if l := logrusLevels[r.Level]; l > level {
+			level = l
+		}
+	}
+
+	for _, s := range p.sinks {
+		if s.Level != "" && s.level > level {
+			level = s.level
+		}
+	}
+
+	p.logger.SetLevel(level)
+}
+
+// isAsyncLevel fatal and panic entries are never buffered, the process is about to stop
+func isAsyncLevel(level logrus.Level) bool {
+	return level > logrus.FatalLevel
+}
+
+// gatedHook fires the hook only for the entries enabled by the pipeline
+type gatedHook struct {
+	logrus.Hook
+	pipeline *pipeline
+}
+
+// Fire fires the wrapped hook
+func (h *gatedHook) Fire(entry *logrus.Entry) error {
+	if fired, ok := firedState(entry); ok && fired.suppressed {
+		return nil
+	}
+
+	h.pipeline.mu.RLock()
+	enabled := h.pipeline.enabled(entry)
+	h.pipeline.mu.RUnlock()
+
+	if !enabled {
+		return nil
+	}
+
+	return h.Hook.Fire(entry)
+}
@@ -0,0 +1,191 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// logMessages sets a json console on a buffer, runs f and returns the messages written
+func logMessages(log *Log, f func()) []string {
+	var (
+		buffer   bytes.Buffer
+		messages []string
+	)
+
+	log.logger.Out = &buffer
+	log.SetFormatterType(JSONFormatterType)
+
+	f()
+
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			messages = append(messages, fields["msg"].(string))
+		}
+	}
+
+	return messages
+}
+
+func TestFieldLevels(t *testing.T) {
+	logger := newLogger()
+	logger.SetLevel(InfoLevel).SetFieldLevel(ComponentKey, "webhook", DebugLevel).SetFieldLevel(StepKey, "validate", TraceLevel)
+
+	got := logMessages(logger, func() {
+		logger.SetComponent("webhook")
+		logger.Trace("webhook trace")
+		logger.Debug("webhook debug")
+		logger.SetComponent("controller")
+		logger.Debug("controller debug")
+		logger.Info("controller info")
+		logger.SetStep("validate")
+		logger.Trace("validate trace")
+		logger.SetStep("")
+		logger.SetComponent("")
+		logger.Debug("no component debug")
+		logger.Warn("no component warn")
+	})
+
+	assert.Equal(t, []string{"webhook debug", "controller info", "validate trace", "no component warn"}, got)
+}
+
+func TestFieldLevelsFirstMatch(t *testing.T) {
+	logger := newLogger()
+	logger.SetLevel(DebugLevel).SetFieldLevel(ComponentKey, "controller", ErrorLevel)
+	logger.SetFieldLevel(StepKey, "process", TraceLevel)
+
+	got := logMessages(logger.SetComponent("controller").SetStep("process"), func() {
+		logger.Info("process info")
+		logger.Error("process error")
+	})
+	assert.Equal(t, []string{"process error"}, got)
+
+	// update in place keeps the order
+	logger.SetFieldLevel(ComponentKey, "controller", InfoLevel)
+	assert.Equal(t, []LevelRule{
+		{Key: ComponentKey, Value: "controller", Level: InfoLevel},
+		{Key: StepKey, Value: "process", Level: TraceLevel},
+	}, logger.GetFieldLevels())
+
+	logger.RemoveFieldLevel(ComponentKey, "controller")
+	got = logMessages(logger, func() {
+		logger.Trace("process trace")
+	})
+	assert.Equal(t, []string{"process trace"}, got)
+
+	logger.RemoveFieldLevel(StepKey, "process")
+	assert.Empty(t, logger.GetFieldLevels())
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+}
+
+func TestFieldLevelsShared(t *testing.T) {
+	logger := newLogger()
+	logger.SetLevel(InfoLevel)
+
+	tlog := logger.ThreadLogger().SetComponent("webhook")
+	logger.SetFieldLevel(ComponentKey, "webhook", DebugLevel)
+	logger.SetFieldLevel(ComponentKey, "webhook", "verbose")
+
+	assert.Equal(t, []LevelRule{{Key: ComponentKey, Value: "webhook", Level: DebugLevel}}, tlog.GetFieldLevels())
+
+	got := logMessages(tlog, func() {
+		tlog.Debug("webhook debug")
+		logger.Debug("debug")
+	})
+	assert.Equal(t, []string{"webhook debug"}, got)
+}
+
+func TestAsyncToggledBetweenFireAndFormat(t *testing.T) {
+	console := &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType)
+
+	p := logger.pipeline
+	queue := newAsyncQueue(AsyncConfig{})
+
+	defer queue.close()
+
+	newEntry := func(msg string) *logrus.Entry {
+		entry := logrus.NewEntry(logger.logger)
+		entry.Level, entry.Message = logrus.InfoLevel, msg
+
+		return entry
+	}
+
+	// buffered by the hook, the formatter does not write it again once the async mode is stopped
+	p.setAsync(queue)
+
+	buffered := newEntry("buffered")
+	assert.NoError(t, p.Fire(buffered))
+	p.setAsync(nil)
+
+	b, err := p.Format(buffered)
+	assert.NoError(t, err)
+	assert.Empty(t, b)
+
+	// not buffered by the hook, the formatter writes it even though the async mode is started
+	direct := newEntry("direct")
+	assert.NoError(t, p.Fire(direct))
+	p.setAsync(queue)
+
+	b, err = p.Format(direct)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"msg":"direct"`)
+
+	assert.NoError(t, queue.flush(context.Background()))
+	assert.Equal(t, []string{"buffered"}, memoryMessages(console))
+}
+
+// stateHook keeps the state marked by the pipeline on the entries fired after it
+type stateHook struct {
+	suppressed int
+}
+
+func (h *stateHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *stateHook) Fire(entry *logrus.Entry) error {
+	if fired, ok := firedState(entry); ok && fired.suppressed {
+		h.suppressed++
+	}
+
+	return nil
+}
+
+func TestFormatterReplaced(t *testing.T) {
+	console, memory := &MemoryWriter{}, &MemoryWriter{}
+	hook := &stateHook{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.logger.AddHook(hook)
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+	assert.Nil(t, logger.EnableSampling(SamplingConfig{Interval: time.Hour, First: 1}))
+
+	defer logger.DisableSampling()
+
+	// the pipeline is not the formatter anymore, it never sees the entries formatted
+	logger.GetEntry().Logger.SetFormatter(&logrus.JSONFormatter{})
+
+	for i := 0; i < 1000; i++ {
+		logger.Info("process item")
+	}
+
+	assert.Len(t, console.Lines(), 1000)
+	assert.Len(t, memory.Lines(), 1)
+	assert.Equal(t, 999, hook.suppressed)
+
+	// the state is kept by the entries only
+	assert.Nil(t, logger.Entry.Context)
+	assert.Nil(t, logger.GetEntry().Context)
+}
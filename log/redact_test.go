@@ -0,0 +1,94 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+type auditUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Pin      string `json:"pin" redact:"true"`
+}
+
+func TestSetRedactor(t *testing.T) {
+	console, memory := &MemoryWriter{}, &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel).SetRedactor(redact.Default())
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+
+	logger.SetUser("johnny").LogAuditAPI("POST", "/login", `{"user":"johnny","password":"secret1"}`,
+		`{"token":"t1"}`, 200)
+	logger.WithField("authorization", "Basic abc").Info("login johnny@example.com")
+
+	for _, lines := range [][]map[string]interface{}{jsonLines(console), jsonLines(memory)} {
+		assert.Len(t, lines, 2)
+		assert.Equal(t, `{"password":"[REDACTED]","user":"johnny"}`, lines[0]["request"])
+		assert.Equal(t, `{"token":"[REDACTED]"}`, lines[0]["response"])
+		assert.Equal(t, "[REDACTED]", lines[1]["authorization"])
+		assert.Equal(t, "login [REDACTED]", lines[1]["msg"])
+	}
+
+	// the fields of the logger are left untouched
+	assert.Equal(t, "johnny", logger.Data[UserKey])
+
+	logger.SetRedactor(nil).Info("login johnny@example.com")
+	assert.Contains(t, console.Lines()[2], "johnny@example.com")
+}
+
+func TestLogAuditObjectRedact(t *testing.T) {
+	console := &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel)
+
+	oldUser := auditUser{Name: "johnny", Password: "p1", Pin: "1234"}
+	newUser := auditUser{Name: "johnny", Password: "p2", Pin: "5678"}
+
+	// the struct tags are always honoured
+	logger.LogAuditObject(oldUser, newUser)
+	logger.SetRedactor(redact.Default()).LogAuditObject(oldUser, newUser)
+
+	lines := jsonLines(console)
+	assert.Len(t, lines, 2)
+
+	assert.Equal(t, `{"name":"johnny","password":"p1","pin":"[REDACTED]"}`, lines[0]["oldObject"])
+	assert.Contains(t, lines[0]["objectDiff"], "([Password], update, p1, p2)")
+	assert.Contains(t, lines[0]["objectDiff"], "([Pin], update, [REDACTED], [REDACTED])")
+
+	assert.Equal(t, `{"name":"johnny","password":"[REDACTED]","pin":"[REDACTED]"}`, lines[1]["oldObject"])
+	assert.Contains(t, lines[1]["objectDiff"], "([Password], update, [REDACTED], [REDACTED])")
+
+	for _, line := range console.Lines() {
+		assert.False(t, strings.Contains(line, "1234") || strings.Contains(line, "5678"))
+	}
+}
+
+func TestNewFromConfigRedact(t *testing.T) {
+	_, err := NewFromConfig(Config{Redact: &redact.Config{Patterns: []string{"("}}})
+	assert.NotNil(t, err)
+
+	cfg, err := ParseConfig([]byte("redact:\n  fields: [pin]\n  paths: [$.spec.code]\n  noDefaults: true\n"))
+	assert.Nil(t, err)
+
+	cl, err := NewFromConfig(cfg)
+	assert.Nil(t, err)
+
+	memory := &MemoryWriter{}
+	logger := cl.(*Log)
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+
+	logger.WithField("pin", 1234).WithField("body", `{"spec":{"code":"c1"}}`).WithField("password", "p1").Info("test")
+
+	lines := jsonLines(memory)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "[REDACTED]", lines[0]["pin"])
+	assert.Equal(t, `{"spec":{"code":"[REDACTED]"}}`, lines[0]["body"])
+	assert.Equal(t, "p1", lines[0]["password"])
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// LevelConfig level, format and per component levels of a logger
+type LevelConfig struct {
+	Level      LevelLog            `json:"level,omitempty" yaml:"level,omitempty"`
+	Format     FormatterType       `json:"format,omitempty" yaml:"format,omitempty"`
+	Components map[string]LevelLog `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// LevelHandler returns a handler reporting (GET) and changing (PUT/POST) the level of the logger.
+// The body is a LevelConfig in json, a component with an empty level removes its override.
+func LevelHandler(l CommonLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var cfg LevelConfig
+
+			if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+				http.Error(w, fmt.Sprintf("invalid level config: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			if err := cfg.validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			cfg.apply(l, false)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(currentLevelConfig(l))
+	})
+}
+
+// ReloadLevelConfig applies the level, format and component levels of the config file (see LoadConfigFile)
+// to the logger. Component levels not in the file are removed.
+func ReloadLevelConfig(l CommonLog, filename string) error {
+	cfg, err := LoadConfigFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.LevelConfig.validate(); err != nil {
+		return fmt.Errorf("invalid level config %s: %v", filename, err)
+	}
+
+	cfg.LevelConfig.apply(l, true)
+
+	return nil
+}
+
+// WatchLevelConfig reloads the config file (see ReloadLevelConfig) every time the process receives
+// one of the signals (SIGHUP when none is given). Reload errors are logged. The returned function stops watching.
+func WatchLevelConfig(l CommonLog, filename string, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	signal.Notify(sigCh, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				if err := ReloadLevelConfig(l, filename); err != nil {
+					l.WithError(err).Error("reload level config failed")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigCh)
+			close(done)
+		})
+	}
+}
+
+func currentLevelConfig(l CommonLog) LevelConfig {
+	cfg := LevelConfig{Level: l.GetLevel(), Components: map[string]LevelLog{}}
+
+	for _, r := range l.GetFieldLevels() {
+		if r.Key == ComponentKey {
+			cfg.Components[r.Value] = r.Level
+		}
+	}
+
+	return cfg
+}
+
+func (c *LevelConfig) validate() error {
+	if c.Level != "" {
+		if _, err := ParseLevel(string(c.Level)); err != nil {
+			return err
+		}
+	}
+
+	if c.Format != "" && !isFormatterType(c.Format) {
+		return fmt.Errorf("invalid formatter type %q", c.Format)
+	}
+
+	for component, level := range c.Components {
+		if level == "" {
+			continue
+		}
+
+		if _, err := ParseLevel(string(level)); err != nil {
+			return fmt.Errorf("component %s: %v", component, err)
+		}
+	}
+
+	return nil
+}
+
+// apply sets the config on the logger, replace removes the component levels missing in the config
+func (c *LevelConfig) apply(l CommonLog, replace bool) {
+	if c.Level != "" {
+		l.SetLevel(c.Level)
+	}
+
+	if c.Format != "" {
+		l.SetFormatterType(c.Format)
+	}
+
+	if replace {
+		for _, r := range l.GetFieldLevels() {
+			if _, ok := c.Components[r.Value]; r.Key == ComponentKey && !ok {
+				l.RemoveFieldLevel(ComponentKey, r.Value)
+			}
+		}
+	}
+
+	for component, level := range c.Components {
+		if level == "" {
+			l.RemoveFieldLevel(ComponentKey, component)
+		} else {
+			l.SetFieldLevel(ComponentKey, component, level)
+		}
+	}
+}
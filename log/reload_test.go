@@ -0,0 +1,134 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	logger := newLogger().SetLevel(InfoLevel)
+	server := httptest.NewServer(LevelHandler(logger))
+
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		want     LevelConfig
+	}{
+		{
+			name: "get", method: http.MethodGet, wantCode: http.StatusOK,
+			want: LevelConfig{Level: InfoLevel},
+		},
+		{
+			name: "set level", method: http.MethodPut, body: `{"level": "warn"}`, wantCode: http.StatusOK,
+			want: LevelConfig{Level: WarnLevel},
+		},
+		{
+			name: "set component level", method: http.MethodPost, wantCode: http.StatusOK,
+			body: `{"components": {"webhook": "debug", "controller": "error"}}`,
+			want: LevelConfig{Level: WarnLevel, Components: map[string]LevelLog{"webhook": DebugLevel, "controller": ErrorLevel}},
+		},
+		{
+			name: "remove component level", method: http.MethodPut, wantCode: http.StatusOK,
+			body: `{"level": "info", "components": {"controller": ""}}`,
+			want: LevelConfig{Level: InfoLevel, Components: map[string]LevelLog{"webhook": DebugLevel}},
+		},
+		{name: "invalid level", method: http.MethodPut, body: `{"level": "verbose"}`, wantCode: http.StatusBadRequest},
+		{name: "invalid body", method: http.MethodPut, body: `level=debug`, wantCode: http.StatusBadRequest},
+		{name: "invalid method", method: http.MethodDelete, wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+
+			if tt.wantCode == http.StatusOK {
+				var got LevelConfig
+				_ = json.NewDecoder(resp.Body).Decode(&got)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	assert.Equal(t, InfoLevel, logger.GetLevel())
+}
+
+func writeLevelConfig(t *testing.T, filename, data string) {
+	if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+}
+
+func TestReloadLevelConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "reload")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "log.json")
+	logger := newLogger().SetLevel(InfoLevel).SetFieldLevel(ComponentKey, "controller", DebugLevel)
+
+	writeLevelConfig(t, filename, `{"level": "error", "format": "json", "components": {"webhook": "trace"}}`)
+	assert.Nil(t, ReloadLevelConfig(logger, filename))
+	assert.Equal(t, ErrorLevel, logger.GetLevel())
+	assert.Equal(t, []LevelRule{{Key: ComponentKey, Value: "webhook", Level: TraceLevel}}, logger.GetFieldLevels())
+
+	writeLevelConfig(t, filename, `{"level": "verbose"}`)
+	assert.NotNil(t, ReloadLevelConfig(logger, filename))
+	writeLevelConfig(t, filename, `{"format": "xml"}`)
+	assert.NotNil(t, ReloadLevelConfig(logger, filename))
+	writeLevelConfig(t, filename, `{"components": {"webhook": "verbose"}}`)
+	assert.NotNil(t, ReloadLevelConfig(logger, filename))
+	writeLevelConfig(t, filename, `level: [debug`)
+	assert.NotNil(t, ReloadLevelConfig(logger, filename))
+	assert.NotNil(t, ReloadLevelConfig(logger, filepath.Join(dir, "missing.json")))
+
+	// failed reloads leave the logger unchanged
+	assert.Equal(t, ErrorLevel, logger.GetLevel())
+}
+
+func TestWatchLevelConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "reload")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "log.json")
+	logger := newLogger().SetLevel(InfoLevel)
+
+	stop := WatchLevelConfig(logger, filename)
+	defer stop()
+
+	writeLevelConfig(t, filename, `{"level": "trace", "components": {"webhook": "warn"}}`)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+
+	for i := 0; i < 100 && logger.GetLevel() != TraceLevel; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, TraceLevel, logger.GetLevel())
+	assert.Equal(t, []LevelRule{{Key: ComponentKey, Value: "webhook", Level: WarnLevel}}, logger.GetFieldLevels())
+
+	stop()
+	stop()
+}
@@ -0,0 +1,335 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RotationInterval time based rotation of the log file
+type RotationInterval string
+
+var (
+	// HourlyRotation HourlyRotation
+	HourlyRotation RotationInterval = "hourly"
+	// DailyRotation DailyRotation
+	DailyRotation RotationInterval = "daily"
+)
+
+// patternTokens timestamp tokens allowed in the log file path
+var patternTokens = map[byte]string{
+	'Y': "2006",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+}
+
+// rotatingWriter writes the log file rotated by size (lumberjack) and by time.
+// When the path has timestamp tokens (%Y%m%d...) every rotation period writes a new file,
+// the previous files are compressed and cleaned up here, otherwise lumberjack moves the file aside.
+type rotatingWriter struct {
+	mu     sync.Mutex
+	cfg    FileConfig
+	writer *lumberjack.Logger
+	next   time.Time
+	now    func() time.Time
+
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+func newRotatingWriter(cfg FileConfig) *rotatingWriter {
+	w := &rotatingWriter{cfg: cfg, now: time.Now}
+	w.open(w.now())
+
+	return w
+}
+
+// Write writes to the log file, rotating it first when the rotation period is over
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if now := w.now(); !w.next.IsZero() && !now.Before(w.next) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	return w.writer.Write(p)
+}
+
+// Rotate closes the log file and starts a new one
+func (w *rotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rotate(w.now())
+}
+
+// Close closes the log file
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.millWg.Wait()
+
+	return w.writer.Close()
+}
+
+func (w *rotatingWriter) open(now time.Time) {
+	w.writer = &lumberjack.Logger{
+		Filename:   formatPattern(w.cfg.Path, periodStart(w.cfg.Rotation, now)),
+		MaxSize:    w.cfg.MaxSize,
+		MaxBackups: w.cfg.MaxBackup,
+		MaxAge:     w.cfg.MaxAge,
+		Compress:   w.cfg.Compress,
+	}
+	w.next = periodEnd(w.cfg.Rotation, now)
+}
+
+func (w *rotatingWriter) rotate(now time.Time) error {
+	filename := formatPattern(w.cfg.Path, periodStart(w.cfg.Rotation, now))
+	if filename == w.writer.Filename {
+		w.next = periodEnd(w.cfg.Rotation, now)
+		return w.writer.Rotate()
+	}
+
+	// new file for the new period
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+
+	w.open(now)
+	w.millWg.Add(1)
+
+	go w.mill(filename, now)
+
+	return nil
+}
+
+// mill compresses and removes the files of the previous periods
+func (w *rotatingWriter) mill(current string, now time.Time) {
+	defer w.millWg.Done()
+
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+
+	files := patternFiles(w.cfg.Path, current)
+	cutoff := now.Add(-time.Duration(w.cfg.MaxAge) * 24 * time.Hour)
+
+	for i, f := range files {
+		if (w.cfg.MaxBackup > 0 && i >= w.cfg.MaxBackup) || (w.cfg.MaxAge > 0 && f.ModTime().Before(cutoff)) {
+			_ = os.Remove(f.name)
+			continue
+		}
+
+		if w.cfg.Compress && !strings.HasSuffix(f.name, ".gz") {
+			_ = compressFile(f.name)
+		}
+	}
+}
+
+type patternFile struct {
+	os.FileInfo
+	name string
+}
+
+// lumberjackBackupTime time format of the backups of lumberjack (name-<time>.ext)
+const lumberjackBackupTime = "2006-01-02T15-04-05.000"
+
+// patternFiles returns the files of the pattern but the current one and its size rotated backups, managed by
+// lumberjack, newest first (by name for the same time)
+func patternFiles(pattern, current string) []patternFile {
+	var files []patternFile
+
+	glob := patternGlob(pattern)
+
+	// the size rotated backups of the previous files are listed too
+	wide := replaceTokens(pattern, func(string) string { return "*" })
+	matches, _ := filepath.Glob(wide)
+	gzMatches, _ := filepath.Glob(wide + ".gz")
+
+	for _, name := range append(matches, gzMatches...) {
+		if name == current || isBackupOf(name, current) || !isPatternFile(name, glob) {
+			continue
+		}
+
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			files = append(files, patternFile{FileInfo: info, name: name})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].ModTime().Equal(files[j].ModTime()) {
+			return files[i].name > files[j].name
+		}
+
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	return files
+}
+
+// isBackupOf checks the file is a backup of the log file written by lumberjack, compressed or not
+func isBackupOf(name, filename string) bool {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filename, ext) + "-"
+
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return false
+	}
+
+	_, err := time.Parse(lumberjackBackupTime, name[len(prefix):len(name)-len(ext)])
+
+	return err == nil
+}
+
+// isPatternFile checks the file is a file of the pattern glob or a lumberjack backup of one, compressed or not
+func isPatternFile(name, glob string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	if ok, _ := filepath.Match(glob, name); ok {
+		return true
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	i := len(base) - len(lumberjackBackupTime) - 1
+	if i <= 0 || base[i] != '-' {
+		return false
+	}
+
+	if _, err := time.Parse(lumberjackBackupTime, base[i+1:]); err != nil {
+		return false
+	}
+
+	ok, _ := filepath.Match(glob, base[:i]+ext)
+
+	return ok
+}
+
+// compressFile gzips the file keeping its modification time, used to order the files
+func compressFile(name string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	gz := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(name+".gz", info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+func periodStart(interval RotationInterval, t time.Time) time.Time {
+	switch interval {
+	case HourlyRotation:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case DailyRotation:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return t
+	}
+}
+
+// periodEnd returns the next rotation time, zero when not rotating on time
+func periodEnd(interval RotationInterval, t time.Time) time.Time {
+	switch interval {
+	case HourlyRotation:
+		return periodStart(interval, t).Add(time.Hour)
+	case DailyRotation:
+		return periodStart(interval, t).AddDate(0, 0, 1)
+	default:
+		return time.Time{}
+	}
+}
+
+// formatPattern replaces the %Y %m %d %H %M %S tokens of the path with the time, %% is a %
+func formatPattern(pattern string, t time.Time) string {
+	return replaceTokens(pattern, func(layout string) string {
+		return t.Format(layout)
+	})
+}
+
+// patternGlob replaces the timestamp tokens of the path with a glob of their digits, [0-9][0-9] for %m
+func patternGlob(pattern string) string {
+	return replaceTokens(pattern, func(layout string) string {
+		return strings.Repeat("[0-9]", len(layout))
+	})
+}
+
+func replaceTokens(pattern string, replace func(layout string) string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+
+		if layout, ok := patternTokens[pattern[i]]; ok {
+			b.WriteString(replace(layout))
+		} else {
+			b.WriteByte(pattern[i])
+		}
+	}
+
+	return b.String()
+}
+
+func validatePattern(pattern string) error {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			continue
+		}
+
+		i++
+
+		if i == len(pattern) {
+			return fmt.Errorf("invalid log file pattern %q: trailing %%", pattern)
+		}
+
+		if _, ok := patternTokens[pattern[i]]; !ok && pattern[i] != '%' {
+			return fmt.Errorf("invalid log file pattern %q: unknown token %%%c", pattern, pattern[i])
+		}
+	}
+
+	return nil
+}
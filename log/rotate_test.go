@@ -0,0 +1,299 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestRotatingWriter(cfg FileConfig, clock *testClock) *rotatingWriter {
+	w := &rotatingWriter{cfg: cfg, now: clock.Now}
+	w.open(clock.now)
+
+	return w
+}
+
+func listDir(dir string) []string {
+	var names []string
+
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestFormatPattern(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)
+	digits := func(n int) string { return strings.Repeat("[0-9]", n) }
+
+	tests := []struct {
+		name     string
+		pattern  string
+		want     string
+		wantGlob string
+		wantErr  bool
+	}{
+		{name: "no tokens", pattern: "/var/log/app.log", want: "/var/log/app.log", wantGlob: "/var/log/app.log"},
+		{name: "daily", pattern: "/var/log/app-%Y%m%d.log", want: "/var/log/app-20190801.log",
+			wantGlob: "/var/log/app-" + digits(8) + ".log"},
+		{name: "hourly", pattern: "app-%Y-%m-%dT%H.log", want: "app-2019-08-01T15.log",
+			wantGlob: "app-" + digits(4) + "-" + digits(2) + "-" + digits(2) + "T" + digits(2) + ".log"},
+		{name: "seconds", pattern: "app-%H%M%S.log", want: "app-150405.log", wantGlob: "app-" + digits(6) + ".log"},
+		{name: "escaped", pattern: "app-100%%.log", want: "app-100%.log", wantGlob: "app-100%.log"},
+		{name: "unknown token", pattern: "app-%y.log", wantErr: true},
+		{name: "trailing", pattern: "app.log%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePattern() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, formatPattern(tt.pattern, ts))
+				assert.Equal(t, tt.wantGlob, patternGlob(tt.pattern))
+			}
+		})
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2019, 8, 1, 15, 0, 0, 0, time.UTC), periodStart(HourlyRotation, ts))
+	assert.Equal(t, time.Date(2019, 8, 1, 16, 0, 0, 0, time.UTC), periodEnd(HourlyRotation, ts))
+	assert.Equal(t, time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC), periodStart(DailyRotation, ts))
+	assert.Equal(t, time.Date(2019, 8, 2, 0, 0, 0, 0, time.UTC), periodEnd(DailyRotation, ts))
+	assert.Equal(t, ts, periodStart("", ts))
+	assert.True(t, periodEnd("", ts).IsZero())
+}
+
+func TestDailyPatternRotation(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rotate")
+	defer os.RemoveAll(dir)
+
+	clock := &testClock{now: time.Date(2019, 8, 1, 23, 0, 0, 0, time.UTC)}
+	w := newTestRotatingWriter(FileConfig{
+		Path: filepath.Join(dir, "app-%Y%m%d.log"), Rotation: DailyRotation, Compress: true, MaxBackup: 2,
+	}, clock)
+
+	for day := 0; day < 4; day++ {
+		_, err := w.Write([]byte("line\n"))
+		assert.Nil(t, err)
+
+		// same day, same file
+		clock.now = clock.now.Add(30 * time.Minute)
+		_, _ = w.Write([]byte("line\n"))
+
+		clock.now = clock.now.Add(24 * time.Hour)
+		w.millWg.Wait()
+	}
+
+	assert.Nil(t, w.Close())
+	assert.Equal(t, []string{"app-20190803.log.gz", "app-20190804.log.gz", "app-20190805.log"}, listDir(dir))
+
+	f, _ := os.Open(filepath.Join(dir, "app-20190804.log.gz"))
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	assert.Nil(t, err)
+
+	data, _ := ioutil.ReadAll(gz)
+	assert.Equal(t, "line\nline\n", string(data))
+}
+
+func TestSizeAndDailyRotation(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rotate")
+	defer os.RemoveAll(dir)
+
+	clock := &testClock{now: time.Date(2019, 8, 1, 23, 0, 0, 0, time.UTC)}
+	w := newTestRotatingWriter(FileConfig{
+		Path: filepath.Join(dir, "app-%Y%m%d.log"), Rotation: DailyRotation, MaxBackup: 1,
+	}, clock)
+
+	// size rotation (lumberjack backup) of the first day
+	_, _ = w.Write([]byte("first\n"))
+	assert.Nil(t, w.Rotate())
+	_, _ = w.Write([]byte("first\n"))
+
+	clock.now = clock.now.Add(time.Hour)
+	_, _ = w.Write([]byte("second\n"))
+	w.millWg.Wait()
+
+	// size rotation of the current day while the previous days are cleaned up
+	assert.Nil(t, w.Rotate())
+	_, _ = w.Write([]byte("second\n"))
+
+	current := filepath.Join(dir, "app-20190802.log")
+	w.millWg.Add(1)
+	w.mill(current, clock.now)
+
+	assert.Nil(t, w.Close())
+
+	files := listDir(dir)
+	if assert.Len(t, files, 3) {
+		assert.Equal(t, "app-20190801.log", files[0])
+		assert.True(t, isBackupOf(filepath.Join(dir, files[1]), current), files[1])
+		assert.Equal(t, "app-20190802.log", files[2])
+	}
+}
+
+func TestPatternLookAlikeFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rotate")
+	defer os.RemoveAll(dir)
+
+	// files of the directory matching app-*.log but not the pattern
+	others := []string{"app-debug.log", "app-2019.log", "app-debug-2019-08-01T10-00-00.000.log", "app-x.log.gz"}
+	for _, name := range others {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("keep\n"), 0644))
+	}
+
+	clock := &testClock{now: time.Date(2019, 8, 1, 23, 0, 0, 0, time.UTC)}
+	w := newTestRotatingWriter(FileConfig{
+		Path: filepath.Join(dir, "app-%Y%m%d.log"), Rotation: DailyRotation, Compress: true, MaxBackup: 1,
+	}, clock)
+
+	for day := 0; day < 3; day++ {
+		_, _ = w.Write([]byte("line\n"))
+		clock.now = clock.now.Add(24 * time.Hour)
+		w.millWg.Wait()
+	}
+
+	assert.Nil(t, w.Close())
+
+	want := append([]string{"app-20190802.log.gz", "app-20190803.log"}, others...)
+	sort.Strings(want)
+	assert.Equal(t, want, listDir(dir))
+}
+
+func TestIsPatternFile(t *testing.T) {
+	glob := patternGlob("/var/log/app-%Y%m%d.log")
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/var/log/app-20190801.log", true},
+		{"/var/log/app-20190801.log.gz", true},
+		{"/var/log/app-20190801-2019-08-01T10-00-00.000.log", true},
+		{"/var/log/app-20190801-2019-08-01T10-00-00.000.log.gz", true},
+		{"/var/log/app-debug.log", false},
+		{"/var/log/app-2019080.log", false},
+		{"/var/log/app-debug-2019-08-01T10-00-00.000.log", false},
+		{"/var/log/app-20190801-backup.log", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isPatternFile(tt.name, glob), tt.name)
+	}
+}
+
+func TestIsBackupOf(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/var/log/app-20190801-2019-08-01T15-04-05.000.log", true},
+		{"/var/log/app-20190801-2019-08-01T15-04-05.000.log.gz", true},
+		{"/var/log/app-20190801.log", false},
+		{"/var/log/app-20190802-2019-08-01T15-04-05.000.log", false},
+		{"/var/log/app-20190801-old.log", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isBackupOf(tt.name, "/var/log/app-20190801.log"), tt.name)
+	}
+}
+
+func TestHourlyRotation(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rotate")
+	defer os.RemoveAll(dir)
+
+	clock := &testClock{now: time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)}
+	w := newTestRotatingWriter(FileConfig{Path: filepath.Join(dir, "app.log"), Rotation: HourlyRotation}, clock)
+
+	_, _ = w.Write([]byte("first\n"))
+	clock.now = clock.now.Add(10 * time.Minute)
+	_, _ = w.Write([]byte("first\n"))
+	assert.Equal(t, []string{"app.log"}, listDir(dir))
+
+	clock.now = clock.now.Add(time.Hour)
+	_, _ = w.Write([]byte("second\n"))
+	assert.Nil(t, w.Close())
+
+	files := listDir(dir)
+	assert.Equal(t, 2, len(files))
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Equal(t, "second\n", string(data))
+}
+
+func TestLogRotate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rotate")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	logger, err := NewFromConfig(Config{File: FileConfig{Path: filename}})
+	assert.Nil(t, err)
+
+	logger.Info("before rotate")
+	assert.Nil(t, logger.Rotate())
+
+	// external tooling moved the file away
+	_ = os.Rename(filename, filepath.Join(dir, "moved.log"))
+	assert.Nil(t, logger.Rotate())
+	logger.Info("after rotate")
+
+	data, _ := ioutil.ReadFile(filename)
+	assert.Contains(t, string(data), "after rotate")
+	assert.NotContains(t, string(data), "before rotate")
+	assert.Equal(t, 3, len(listDir(dir)))
+
+	// no file, nothing to rotate
+	assert.Nil(t, NewLogger().Rotate())
+}
+
+func TestFileConfigRotation(t *testing.T) {
+	for _, cfg := range []FileConfig{
+		{Path: "/tmp/app.log", Rotation: "weekly"},
+		{Path: "/tmp/app-%q.log"},
+	} {
+		c := Config{File: cfg}
+		assert.NotNil(t, c.Validate())
+	}
+
+	_ = os.Setenv("GOUTILS_LOG_FILE_COMPRESS", "yes please")
+	defer os.Unsetenv("GOUTILS_LOG_FILE_COMPRESS")
+
+	c := Config{}
+	assert.NotNil(t, c.LoadEnv())
+
+	_ = os.Setenv("GOUTILS_LOG_FILE_COMPRESS", "true")
+	_ = os.Setenv("GOUTILS_LOG_FILE_ROTATION", "daily")
+	defer os.Unsetenv("GOUTILS_LOG_FILE_ROTATION")
+
+	assert.Nil(t, c.LoadEnv())
+	assert.Equal(t, FileConfig{Rotation: DailyRotation, Compress: true}, c.File)
+}
@@ -0,0 +1,257 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSamplingInterval sampling interval used when none is configured
+	DefaultSamplingInterval = time.Second
+	// DefaultSamplingSummaryInterval summary interval used when none is configured
+	DefaultSamplingSummaryInterval = time.Minute
+	// SuppressedKey field of the summary line with the number of suppressed entries, the entries having it are
+	// never suppressed
+	SuppressedKey = "suppressed"
+)
+
+// SamplingConfig sampling and rate limiting of the entries, fatal and panic entries are never suppressed
+type SamplingConfig struct {
+	// Interval sampling period, DefaultSamplingInterval when 0
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// First entries with the same level and message written every interval, no sampling when 0
+	First int `json:"first,omitempty" yaml:"first,omitempty"`
+	// Thereafter every Thereafter-th entry is written after the first ones, none when 0
+	Thereafter int `json:"thereafter,omitempty" yaml:"thereafter,omitempty"`
+	// RateLimit token bucket limiting per value of a field
+	RateLimit RateLimitConfig `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	// SummaryInterval period of the summary line of the suppressed entries, DefaultSamplingSummaryInterval when 0
+	SummaryInterval time.Duration `json:"summaryInterval,omitempty" yaml:"summaryInterval,omitempty"`
+}
+
+// RateLimitConfig token bucket limiting of the entries per value of the field Key (e.g. objectName),
+// the entries without the field are not limited
+type RateLimitConfig struct {
+	// Key field name, no limiting when empty
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Rate entries per second
+	Rate float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	// Burst entries written at once, 1 when 0
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+func (c *SamplingConfig) validate() error {
+	if c.Interval < 0 || c.SummaryInterval < 0 {
+		return fmt.Errorf("invalid sampling: intervals can not be negative")
+	}
+
+	if c.First < 0 || c.Thereafter < 0 {
+		return fmt.Errorf("invalid sampling: first and thereafter can not be negative")
+	}
+
+	if c.RateLimit.Key != "" && c.RateLimit.Rate <= 0 {
+		return fmt.Errorf("invalid rate limit of %s: rate must be positive", c.RateLimit.Key)
+	}
+
+	if c.RateLimit.Burst < 0 {
+		return fmt.Errorf("invalid rate limit of %s: burst can not be negative", c.RateLimit.Key)
+	}
+
+	return nil
+}
+
+type sampleKey struct {
+	level   logrus.Level
+	message string
+}
+
+// suppressedCount entries suppressed by the sampling and by the rate limit
+type suppressedCount struct {
+	sampled uint64
+	limited uint64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sampler suppresses the entries over the sampling and the rate limits, and counts them for the summary
+type sampler struct {
+	mu     sync.Mutex
+	cfg    SamplingConfig
+	now    func() time.Time
+	window time.Time
+	counts map[sampleKey]int
+	// buckets rate limit per field value, the full ones are removed with the sampling counts
+	buckets map[string]*tokenBucket
+	// levels suppressed entries per level, for the summary lines
+	levels map[logrus.Level]suppressedCount
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSampler(cfg SamplingConfig) *sampler {
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultSamplingInterval
+	}
+
+	if cfg.SummaryInterval == 0 {
+		cfg.SummaryInterval = DefaultSamplingSummaryInterval
+	}
+
+	if cfg.RateLimit.Burst == 0 {
+		cfg.RateLimit.Burst = 1
+	}
+
+	return &sampler{
+		cfg:     cfg,
+		now:     time.Now,
+		counts:  map[sampleKey]int{},
+		buckets: map[string]*tokenBucket{},
+		levels:  map[logrus.Level]suppressedCount{},
+	}
+}
+
+// allow checks the entry against the sampling then the rate limit
+func (s *sampler) allow(entry *logrus.Entry) bool {
+	if entry.Level <= logrus.FatalLevel {
+		return true
+	}
+
+	if _, ok := entry.Data[SuppressedKey]; ok {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if now.Sub(s.window) >= s.cfg.Interval {
+		s.window = now
+		s.counts = map[sampleKey]int{}
+		s.pruneBuckets(now)
+	}
+
+	if s.cfg.First > 0 {
+		key := sampleKey{level: entry.Level, message: entry.Message}
+		s.counts[key]++
+
+		if n := s.counts[key] - s.cfg.First; n > 0 && (s.cfg.Thereafter == 0 || n%s.cfg.Thereafter != 0) {
+			c := s.levels[entry.Level]
+			c.sampled++
+			s.levels[entry.Level] = c
+
+			return false
+		}
+	}
+
+	if s.cfg.RateLimit.Key != "" {
+		if v, ok := entry.Data[s.cfg.RateLimit.Key]; ok && !s.take(fmt.Sprint(v), now) {
+			c := s.levels[entry.Level]
+			c.limited++
+			s.levels[entry.Level] = c
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// take takes a token of the bucket of the value
+func (s *sampler) take(value string, now time.Time) bool {
+	burst := float64(s.cfg.RateLimit.Burst)
+
+	b, ok := s.buckets[value]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[value] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * s.cfg.RateLimit.Rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+func (s *sampler) pruneBuckets(now time.Time) {
+	burst := float64(s.cfg.RateLimit.Burst)
+
+	for value, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*s.cfg.RateLimit.Rate >= burst {
+			delete(s.buckets, value)
+		}
+	}
+}
+
+// suppressed returns and resets the suppressed counts per level
+func (s *sampler) suppressed() map[logrus.Level]suppressedCount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	levels := s.levels
+	s.levels = map[logrus.Level]suppressedCount{}
+
+	return levels
+}
+
+// start writes the summary line every summary interval when entries were suppressed
+func (s *sampler) start(l *Log) {
+	s.done = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.cfg.SummaryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.summary(l)
+			case <-s.done:
+				s.summary(l)
+				return
+			}
+		}
+	}()
+}
+
+// stop stops the summary, the last one is written
+func (s *sampler) stop() {
+	close(s.done)
+	<-s.stopped
+}
+
+// summary writes a summary line per level at the level of the entries suppressed, so it passes the same level
+// checks they did
+func (s *sampler) summary(l *Log) {
+	levels := s.suppressed()
+
+	for _, level := range logrus.AllLevels {
+		c, ok := levels[level]
+		if !ok {
+			continue
+		}
+
+		l.WithField(SuppressedKey, c.sampled+c.limited).WithField("sampled", c.sampled).WithField(
+			"limited", c.limited).Log(level, "log entries suppressed")
+	}
+}
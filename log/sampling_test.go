@@ -0,0 +1,187 @@
+package log
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplerAllow(t *testing.T) {
+	clock := &testClock{now: time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)}
+
+	s := newSampler(SamplingConfig{First: 2, Thereafter: 3})
+	s.now = clock.Now
+
+	entry := func(level logrus.Level, message string) *logrus.Entry {
+		e := logrus.NewEntry(logrus.New())
+		e.Level, e.Message = level, message
+
+		return e
+	}
+
+	var got []bool
+	for i := 0; i < 8; i++ {
+		got = append(got, s.allow(entry(logrus.InfoLevel, "process item")))
+	}
+
+	assert.Equal(t, []bool{true, true, false, false, true, false, false, true}, got)
+
+	// other level, other message, fatal entries and summary lines have their own counts
+	assert.True(t, s.allow(entry(logrus.DebugLevel, "process item")))
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "other item")))
+	assert.True(t, s.allow(entry(logrus.FatalLevel, "process item")))
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "process item").WithField(SuppressedKey, 1)))
+
+	// new interval
+	clock.now = clock.now.Add(time.Second)
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "process item")))
+
+	assert.Equal(t, map[logrus.Level]suppressedCount{logrus.InfoLevel: {sampled: 4}}, s.suppressed())
+	assert.Empty(t, s.suppressed())
+}
+
+func TestSamplerRateLimit(t *testing.T) {
+	clock := &testClock{now: time.Date(2019, 8, 1, 15, 4, 5, 0, time.UTC)}
+
+	s := newSampler(SamplingConfig{RateLimit: RateLimitConfig{Key: ObjectNameKey, Rate: 2, Burst: 2}})
+	s.now = clock.Now
+
+	allow := func(fields logrus.Fields) bool {
+		e := logrus.NewEntry(logrus.New()).WithFields(fields)
+		e.Level, e.Message = logrus.InfoLevel, "process item"
+
+		return s.allow(e)
+	}
+
+	iphone, ipad := logrus.Fields{ObjectNameKey: "iphone"}, logrus.Fields{ObjectNameKey: "ipad"}
+
+	assert.True(t, allow(iphone))
+	assert.True(t, allow(iphone))
+	assert.False(t, allow(iphone))
+	assert.True(t, allow(ipad))
+	assert.True(t, allow(logrus.Fields{}))
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.True(t, allow(iphone))
+	assert.False(t, allow(iphone))
+
+	// full buckets are removed with the sampling counts
+	clock.now = clock.now.Add(2 * time.Second)
+	assert.True(t, allow(iphone))
+	assert.Len(t, s.buckets, 1)
+
+	assert.Equal(t, map[logrus.Level]suppressedCount{logrus.InfoLevel: {limited: 2}}, s.suppressed())
+}
+
+func TestEnableSampling(t *testing.T) {
+	console, memory := &MemoryWriter{}, &MemoryWriter{}
+	hook := &countHook{}
+
+	cl, err := NewFromConfig(Config{
+		Sampling: &SamplingConfig{Interval: time.Hour, First: 2, Thereafter: 4},
+		Hooks:    []logrus.Hook{hook},
+	})
+	assert.Nil(t, err)
+
+	logger := cl.(*Log)
+	logger.logger.Out = console
+	logger.SetFormatterType(JSONFormatterType)
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+
+	for i := 0; i < 10; i++ {
+		logger.ThreadLogger().Info("process item")
+	}
+
+	assert.Len(t, memoryMessages(console), 4)
+	assert.Len(t, memoryMessages(memory), 4)
+	assert.Equal(t, 4, hook.count)
+
+	logger.DisableSampling()
+
+	lines := memory.Lines()
+	assert.Len(t, lines, 5)
+	assert.Contains(t, lines[4], `"msg":"log entries suppressed"`)
+	assert.Contains(t, lines[4], `"sampled":6`)
+	assert.Contains(t, lines[4], `"suppressed":6`)
+
+	// nothing suppressed anymore
+	logger.Info("process item")
+	assert.Len(t, memory.Lines(), 6)
+}
+
+func TestSamplingSummary(t *testing.T) {
+	memory := &MemoryWriter{}
+
+	logger := newLogger()
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+	assert.Nil(t, logger.EnableSampling(SamplingConfig{
+		RateLimit: RateLimitConfig{Key: ObjectNameKey, Rate: 0.001}, SummaryInterval: 10 * time.Millisecond,
+	}))
+	defer logger.Close()
+
+	logger.SetObjectName("iphone").Info("1")
+	logger.Info("2")
+	logger.Info("3")
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) && len(memory.Lines()) < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	lines := memory.Lines()
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.Contains(lines[1], `"limited":2`), lines[1])
+}
+
+func TestSamplingSummaryLevel(t *testing.T) {
+	memory := &MemoryWriter{}
+
+	logger := newLogger()
+	logger.logger.Out = ioutil.Discard
+	logger.SetLevel(ErrorLevel)
+	assert.Nil(t, logger.AddSink(Sink{Name: "memory", Writer: memory}))
+	assert.Nil(t, logger.EnableSampling(SamplingConfig{Interval: time.Hour, First: 1}))
+
+	for i := 0; i < 3; i++ {
+		logger.Error("sync failed")
+		logger.Warn("not written")
+	}
+
+	logger.DisableSampling()
+
+	// the summary of the error entries is an error, written at the error level
+	lines := jsonLines(memory)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "log entries suppressed", lines[1]["msg"])
+		assert.Equal(t, "error", lines[1]["level"])
+		assert.Equal(t, float64(2), lines[1][SuppressedKey])
+	}
+}
+
+func TestEnableSamplingInvalid(t *testing.T) {
+	logger := newLogger()
+
+	for _, cfg := range []SamplingConfig{
+		{Interval: -time.Second},
+		{First: -1},
+		{RateLimit: RateLimitConfig{Key: ObjectNameKey}},
+		{RateLimit: RateLimitConfig{Key: ObjectNameKey, Rate: 1, Burst: -1}},
+	} {
+		assert.NotNil(t, logger.EnableSampling(cfg), "%+v", cfg)
+	}
+}
+
+func TestParseConfigSampling(t *testing.T) {
+	cfg, err := ParseConfig([]byte("sampling:\n  interval: 2s\n  first: 10\n  thereafter: 100\n" +
+		"  rateLimit:\n    key: objectName\n    rate: 0.5\n  summaryInterval: 5m\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, &SamplingConfig{
+		Interval: 2 * time.Second, First: 10, Thereafter: 100, RateLimit: RateLimitConfig{Key: ObjectNameKey, Rate: 0.5},
+		SummaryInterval: 5 * time.Minute,
+	}, cfg.Sampling)
+}
@@ -0,0 +1,204 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FileSinkName name of the sink of the log file (NewLoggerWithFile, Config.File)
+const FileSinkName = "file"
+
+// Sink named output of a logger, on top of the console, with its own level, format and field filter
+type Sink struct {
+	// Name unique name of the sink in the logger
+	Name string
+	// Writer output of the sink (os.Stdout, os.Stderr, a MemoryWriter, ...)
+	Writer io.Writer
+	// Level most verbose level written, the level of the logger (with the field levels) when empty
+	Level LevelLog
+	// Format formatter type, json unless text, logfmt, ecs or gelf (the syslog sink has its own format)
+	Format FormatterType
+	// Filter writes only the entries whose fields it returns true for, every entry when nil
+	Filter func(fields logrus.Fields) bool
+}
+
+// sink a Sink ready to be fired, replaced as a whole when changed
+type sink struct {
+	Sink
+	level     logrus.Level
+	formatter logrus.Formatter
+}
+
+// MemoryWriter keeps the written lines in memory
+type MemoryWriter struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Write keeps the lines
+func (m *MemoryWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		m.lines = append(m.lines, line)
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the lines written
+func (m *MemoryWriter) Lines() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string{}, m.lines...)
+}
+
+// Reset removes the lines written
+func (m *MemoryWriter) Reset() {
+	m.mu.Lock()
+	m.lines = nil
+	m.mu.Unlock()
+}
+
+// NewFileSink creates a sink writing to the rotated log file of the config, returns an error when
+// the log file can not be written
+func NewFileSink(name string, cfg FileConfig) (Sink, error) {
+	writer, err := newFileWriter(cfg)
+	if err != nil {
+		return Sink{}, err
+	}
+
+	return Sink{Name: name, Writer: writer, Level: cfg.Level, Format: cfg.Format}, nil
+}
+
+func newSink(s Sink) (*sink, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("invalid sink: empty name")
+	}
+
+	if s.Writer == nil {
+		return nil, fmt.Errorf("invalid sink %s: no writer", s.Name)
+	}
+
+	ns := &sink{Sink: s, formatter: formatterOf(s)}
+
+	if s.Level != "" {
+		level, err := toLogrusLevel(s.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink %s: %v", s.Name, err)
+		}
+
+		ns.level = level
+	}
+
+	return ns, nil
+}
+
+// accepts checks the entry against the sink level, or the logger level when the sink has none
+func (s *sink) accepts(entry *logrus.Entry, enabled bool) bool {
+	if s.Level != "" {
+		enabled = entry.Level <= s.level
+	}
+
+	return enabled && (s.Filter == nil || s.Filter(entry.Data))
+}
+
+func (s *sink) fire(entry *logrus.Entry) error {
+	if w, ok := s.Writer.(entryWriter); ok {
+		return s.wrapError(w.prepareEntry(entry)())
+	}
+
+	b, err := s.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.write(b)
+}
+
+func (s *sink) write(b []byte) error {
+	_, err := s.Writer.Write(b)
+	return s.wrapError(err)
+}
+
+// entryWrite returns the async write function of the prepared entry
+func (s *sink) entryWrite(write func() error) func([]byte) error {
+	return func([]byte) error {
+		return s.wrapError(write())
+	}
+}
+
+func (s *sink) wrapError(err error) error {
+	if err != nil {
+		return fmt.Errorf("sink %s: %v", s.Name, err)
+	}
+
+	return nil
+}
+
+// close closes the writers opened by the package
+func (s *sink) close() error {
+	switch w := s.Writer.(type) {
+	case *rotatingWriter:
+		return w.Close()
+	case *gelfWriter:
+		return w.Close()
+	case *syslogWriter:
+		return w.Close()
+	default:
+		return nil
+	}
+}
+
+// formattingWriter writer with its own formatter (syslog)
+type formattingWriter interface {
+	sinkFormatter() logrus.Formatter
+}
+
+// entryWriter writer of the entries rather than formatted bytes (slog), the entry is converted when
+// fired and written by the returned function, later in async mode
+type entryWriter interface {
+	prepareEntry(entry *logrus.Entry) func() error
+}
+
+// formatterOf returns the formatter of the writer, or the formatter of the sink format
+func formatterOf(s Sink) logrus.Formatter {
+	if w, ok := s.Writer.(formattingWriter); ok {
+		return w.sinkFormatter()
+	}
+
+	return sinkFormatter(s.Format)
+}
+
+func sinkFormatter(fType FormatterType) logrus.Formatter {
+	if fType == ECSFormatterType {
+		return &ECSFormatter{}
+	}
+
+	if fType == GELFFormatterType {
+		return &GELFFormatter{}
+	}
+
+	if fType == LogfmtFormatterType {
+		return &LogfmtFormatter{
+			TimestampFormat: RFC3339NanoFixed,
+		}
+	}
+
+	if fType == TextFormatterType {
+		return &logrus.TextFormatter{
+			DisableColors:   true,
+			TimestampFormat: RFC3339NanoFixed,
+		}
+	}
+
+	return &logrus.JSONFormatter{
+		TimestampFormat: RFC3339NanoFixed,
+	}
+}
@@ -0,0 +1,149 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonLines returns the fields of the json lines written to the memory writer, the other lines are skipped
+func jsonLines(m *MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range m.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestSinks(t *testing.T) {
+	logger := newLogger().SetLevel(InfoLevel)
+	debug := &MemoryWriter{}
+	audit := &MemoryWriter{}
+	follow := &MemoryWriter{}
+
+	assert.Nil(t, logger.AddSink(Sink{Name: "debug", Writer: debug, Level: DebugLevel, Format: TextFormatterType}))
+	assert.Nil(t, logger.AddSink(Sink{Name: "audit", Writer: audit, Filter: func(fields logrus.Fields) bool {
+		return fields["auditType"] == "api"
+	}}))
+	assert.Nil(t, logger.AddSink(Sink{Name: "follow", Writer: follow}))
+	assert.Nil(t, logger.AddSink(Sink{Name: "stderr", Writer: os.Stderr, Level: ErrorLevel}))
+	assert.Equal(t, []string{"debug", "audit", "follow", "stderr"}, logger.GetSinks())
+
+	got := logMessages(logger, func() {
+		logger.Debug("debug line")
+		logger.Info("info line")
+		logger.SetLevel(DebugLevel)
+		logger.LogAuditAPI("GET", "/orders", "", "", 200)
+	})
+
+	// console follows the logger level
+	assert.Equal(t, []string{"info line", "audit api"}, got)
+
+	lines := debug.Lines()
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], `level=debug msg="debug line"`)
+
+	lines = audit.Lines()
+	assert.Equal(t, 1, len(lines))
+	assert.Contains(t, lines[0], `"auditType":"api"`)
+
+	assert.Equal(t, 2, len(follow.Lines()))
+
+	assert.Nil(t, logger.RemoveSink("debug"))
+	assert.NotNil(t, logger.RemoveSink("debug"))
+	assert.Equal(t, []string{"audit", "follow", "stderr"}, logger.GetSinks())
+
+	debug.Reset()
+	logger.Debug("after remove")
+	assert.Empty(t, debug.Lines())
+	assert.Equal(t, 3, len(follow.Lines()))
+}
+
+func TestSinkLevels(t *testing.T) {
+	logger := newLogger().SetLevel(WarnLevel).SetFieldLevel(ComponentKey, "webhook", DebugLevel)
+	trace := &MemoryWriter{}
+	follow := &MemoryWriter{}
+
+	assert.Nil(t, logger.AddSink(Sink{Name: "trace", Writer: trace, Level: TraceLevel}))
+	assert.Nil(t, logger.AddSink(Sink{Name: "follow", Writer: follow}))
+
+	// the logger lets trace through for the sink, the console keeps its level
+	assert.Equal(t, WarnLevel, logger.GetLevel())
+	assert.Equal(t, logrus.TraceLevel, logger.logger.GetLevel())
+
+	got := logMessages(logger, func() {
+		logger.Trace("trace")
+		logger.ThreadLogger().SetComponent("webhook").Debug("webhook debug")
+	})
+
+	assert.Equal(t, []string{"webhook debug"}, got)
+	assert.Equal(t, 2, len(trace.Lines()))
+	assert.Equal(t, 1, len(follow.Lines()))
+
+	assert.Nil(t, logger.RemoveSink("trace"))
+	assert.Equal(t, logrus.DebugLevel, logger.logger.GetLevel())
+}
+
+func TestAddSinkErrors(t *testing.T) {
+	logger := newLogger()
+
+	tests := []struct {
+		name string
+		sink Sink
+	}{
+		{name: "empty name", sink: Sink{Writer: &MemoryWriter{}}},
+		{name: "no writer", sink: Sink{Name: "mem"}},
+		{name: "invalid level", sink: Sink{Name: "mem", Writer: &MemoryWriter{}, Level: "verbose"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotNil(t, logger.AddSink(tt.sink))
+		})
+	}
+
+	assert.Nil(t, logger.AddSink(Sink{Name: "mem", Writer: &MemoryWriter{}}))
+	assert.NotNil(t, logger.AddSink(Sink{Name: "mem", Writer: &MemoryWriter{}}))
+}
+
+func TestFileSink(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sink")
+	defer os.RemoveAll(dir)
+
+	logger := newLogger().SetLevel(DebugLevel)
+	logger.logger.Out = ioutil.Discard
+
+	_, err := NewFileSink("bad", FileConfig{})
+	assert.NotNil(t, err)
+
+	audit, err := NewFileSink("audit", FileConfig{Path: filepath.Join(dir, "audit.log"), Level: InfoLevel})
+	assert.Nil(t, err)
+	assert.Nil(t, logger.AddSink(audit))
+
+	all, err := NewFileSink("all", FileConfig{Path: filepath.Join(dir, "all.log"), Format: TextFormatterType})
+	assert.Nil(t, err)
+	assert.Nil(t, logger.AddSink(all))
+
+	logger.Debug("debug line")
+	logger.Info("info line")
+	assert.Nil(t, logger.Rotate())
+	assert.Nil(t, logger.RemoveSink("audit"))
+	logger.Info("after remove")
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	assert.Empty(t, string(data))
+
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "all.log"))
+	assert.Contains(t, string(data), `msg="after remove"`)
+	assert.Equal(t, 4, len(listDir(dir)))
+}
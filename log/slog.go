@@ -0,0 +1,267 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"sort"
+
+	"github.com/golang-collections/collections/stack"
+	"github.com/sirupsen/logrus"
+)
+
+// slog levels of the levels slog does not define
+const (
+	// SlogTraceLevel SlogTraceLevel
+	SlogTraceLevel slog.Level = -8
+	// SlogFatalLevel SlogFatalLevel
+	SlogFatalLevel slog.Level = 12
+	// SlogPanicLevel SlogPanicLevel
+	SlogPanicLevel slog.Level = 16
+)
+
+// SlogSinkName name of the sink of the logger created by NewSlogLogger
+const SlogSinkName = "slog"
+
+var slogLevels = map[LevelLog]slog.Level{
+	TraceLevel: SlogTraceLevel,
+	DebugLevel: slog.LevelDebug,
+	InfoLevel:  slog.LevelInfo,
+	WarnLevel:  slog.LevelWarn,
+	ErrorLevel: slog.LevelError,
+	FatalLevel: SlogFatalLevel,
+	PanicLevel: SlogPanicLevel,
+}
+
+// ToSlogLevel returns the slog level of the level, info for an unknown level
+func ToSlogLevel(level LevelLog) slog.Level {
+	lvl, err := ParseLevel(string(level))
+	if err != nil {
+		return slog.LevelInfo
+	}
+
+	return slogLevels[lvl]
+}
+
+// FromSlogLevel returns the level of the slog level, the custom slog levels are rounded down
+// (e.g. slog.LevelInfo+2 is info)
+func FromSlogLevel(level slog.Level) LevelLog {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	case level < SlogFatalLevel:
+		return ErrorLevel
+	case level < SlogPanicLevel:
+		return FatalLevel
+	default:
+		return PanicLevel
+	}
+}
+
+// slogHandler slog handler writing the records with a logger
+type slogHandler struct {
+	log    CommonLog
+	fields logrus.Fields
+	groups []string
+}
+
+// NewSlogHandler returns a slog handler writing the records with the logger, the attributes of the groups are
+// nested fields and the records above error are errors (a slog call never stops the process)
+func NewSlogHandler(l CommonLog) slog.Handler {
+	return &slogHandler{log: l, fields: logrus.Fields{}}
+}
+
+// Enabled checks the slog level against the logrus logger level
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.log.GetEntry().Logger.IsLevelEnabled(logrusLevels[FromSlogLevel(level)])
+}
+
+// Handle writes the record
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	level := logrusLevels[FromSlogLevel(r.Level)]
+	if level < logrus.ErrorLevel {
+		level = logrus.ErrorLevel
+	}
+
+	entry := h.log.WithFields(withSlogAttrs(h.fields, h.groups, attrs)).WithTime(r.Time)
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+
+	entry.Log(level, r.Message)
+
+	return nil
+}
+
+// WithAttrs returns a handler adding the attributes to the records
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	return &slogHandler{log: h.log, fields: withSlogAttrs(h.fields, h.groups, attrs), groups: h.groups}
+}
+
+// WithGroup returns a handler nesting the attributes in the group
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{log: h.log, fields: h.fields, groups: append(h.groups[:len(h.groups):len(h.groups)], name)}
+}
+
+// withSlogAttrs returns a copy of the fields with the attributes added in the nested group
+func withSlogAttrs(fields logrus.Fields, groups []string, attrs []slog.Attr) logrus.Fields {
+	nf := make(logrus.Fields, len(fields)+len(attrs))
+	for k, v := range fields {
+		nf[k] = v
+	}
+
+	if len(groups) == 0 {
+		for _, a := range attrs {
+			addSlogAttr(nf, a)
+		}
+
+		return nf
+	}
+
+	group, _ := fields[groups[0]].(logrus.Fields)
+	if group = withSlogAttrs(group, groups[1:], attrs); len(group) > 0 {
+		nf[groups[0]] = group
+	}
+
+	return nf
+}
+
+// addSlogAttr adds the attribute to the fields following the slog handler rules: empty attributes and
+// groups are ignored, the attributes of a group without key are inlined
+func addSlogAttr(fields logrus.Fields, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		fields[a.Key] = a.Value.Any()
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	if a.Key == "" {
+		for _, ga := range attrs {
+			addSlogAttr(fields, ga)
+		}
+
+		return
+	}
+
+	group := logrus.Fields{}
+	for _, ga := range attrs {
+		addSlogAttr(group, ga)
+	}
+
+	fields[a.Key] = group
+}
+
+// slogWriter writes the entries of a sink to a slog handler
+type slogWriter struct {
+	handler slog.Handler
+}
+
+// NewSlogSink creates a sink writing the entries to the slog handler, the fields are attributes
+// and the nested fields are groups. The Format of the sink is not used.
+func NewSlogSink(name string, h slog.Handler) Sink {
+	return Sink{Name: name, Writer: &slogWriter{handler: h}}
+}
+
+// NewSlogLogger creates a logger writing through the slog handler (see NewSlogSink), its console output is
+// discarded. The level of the logger is the most verbose level enabled by the handler.
+func NewSlogLogger(h slog.Handler) CommonLog {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	level := PanicLevel
+
+	for _, lvl := range []LevelLog{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel} {
+		if h.Enabled(context.Background(), slogLevels[lvl]) {
+			level = lvl
+			break
+		}
+	}
+
+	l := newLog(logger, stack.New(), stack.New())
+	l.SetLevel(level)
+
+	_ = l.AddSink(NewSlogSink(SlogSinkName, h))
+
+	return l
+}
+
+// Write discards the formatted entries, the entries are written by prepareEntry
+func (w *slogWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *slogWriter) prepareEntry(entry *logrus.Entry) func() error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	level := slogLevels[fromLogrusLevel(entry.Level)]
+	if !w.handler.Enabled(ctx, level) {
+		return func() error { return nil }
+	}
+
+	r := slog.NewRecord(entry.Time, level, entry.Message, 0)
+	r.AddAttrs(slogAttrs(entry.Data)...)
+
+	return func() error {
+		return w.handler.Handle(ctx, r)
+	}
+}
+
+// slogAttrs returns the fields as attributes sorted by key, the nested fields are groups
+func slogAttrs(fields map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case logrus.Fields:
+			attrs = append(attrs, slog.Attr{Key: k, Value: slog.GroupValue(slogAttrs(v)...)})
+		case map[string]interface{}:
+			attrs = append(attrs, slog.Attr{Key: k, Value: slog.GroupValue(slogAttrs(v)...)})
+		default:
+			attrs = append(attrs, slog.Any(k, v))
+		}
+	}
+
+	return attrs
+}
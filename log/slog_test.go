@@ -0,0 +1,118 @@
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLevels(t *testing.T) {
+	tests := []struct {
+		slog  slog.Level
+		level LevelLog
+	}{
+		{SlogTraceLevel, TraceLevel},
+		{slog.LevelDebug - 1, TraceLevel},
+		{slog.LevelDebug, DebugLevel},
+		{slog.LevelInfo, InfoLevel},
+		{slog.LevelInfo + 2, InfoLevel},
+		{slog.LevelWarn, WarnLevel},
+		{slog.LevelError, ErrorLevel},
+		{SlogFatalLevel, FatalLevel},
+		{SlogPanicLevel + 4, PanicLevel},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.level, FromSlogLevel(tt.slog), tt.slog.String())
+	}
+
+	for level, s := range slogLevels {
+		assert.Equal(t, s, ToSlogLevel(level))
+	}
+
+	assert.Equal(t, slog.LevelWarn, ToSlogLevel("WARNING"))
+	assert.Equal(t, slog.LevelInfo, ToSlogLevel("unknown"))
+}
+
+func TestSlogHandler(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType)
+	l.SetCluster("minikube").SetComponent("webhook")
+
+	logger := slog.New(NewSlogHandler(l)).With("requestId", "r1")
+
+	logger.Info("created", "name", "n1", slog.Group("user", "name", "u1", "groups", 2))
+	logger.WithGroup("request").With("method", "GET").WithGroup("empty").Warn("slow", slog.Group("none"))
+	logger.Debug("not written")
+	logger.Log(context.Background(), SlogFatalLevel, "not fatal", "err", errors.New("failed"))
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, "created", fields[0]["msg"])
+		assert.Equal(t, "info", fields[0]["level"])
+		assert.Equal(t, "minikube", fields[0]["cluster"])
+		assert.Equal(t, "webhook", fields[0]["component"])
+		assert.Equal(t, "r1", fields[0]["requestId"])
+		assert.Equal(t, "n1", fields[0]["name"])
+		assert.Equal(t, map[string]interface{}{"name": "u1", "groups": float64(2)}, fields[0]["user"])
+
+		assert.Equal(t, "warning", fields[1]["level"])
+		assert.Equal(t, "r1", fields[1]["requestId"])
+		assert.Equal(t, map[string]interface{}{"method": "GET"}, fields[1]["request"])
+
+		assert.Equal(t, "error", fields[2]["level"])
+		assert.Equal(t, "failed", fields[2]["err"])
+	}
+
+	// level rules
+	l.SetFieldLevel(ComponentKey, "webhook", DebugLevel)
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug-1))
+
+	logger.Debug("written")
+	assert.Len(t, out.Lines(), 4)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	l := NewSlogLogger(h)
+	assert.Equal(t, DebugLevel, l.GetLevel())
+	assert.Equal(t, []string{SlogSinkName}, l.GetSinks())
+
+	l.SetCluster("minikube")
+	l.WithField("request", map[string]interface{}{"method": "GET", "header": map[string]interface{}{"id": "r1"}}).
+		WithError(errors.New("failed")).WithTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)).Warn("slow")
+	l.WithField("step", 1).Trace("not written")
+
+	var record map[string]interface{}
+
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "slow", record["msg"])
+	assert.Equal(t, "2020-01-02T03:04:05Z", record["time"])
+	assert.Equal(t, "minikube", record["cluster"])
+	assert.Equal(t, "failed", record["error"])
+	assert.Equal(t, map[string]interface{}{"method": "GET", "header": map[string]interface{}{"id": "r1"}}, record["request"])
+
+	// async
+	buf.Reset()
+	assert.NoError(t, l.EnableAsync(AsyncConfig{}))
+	l.Info("async")
+	assert.NoError(t, l.Close())
+	assert.Contains(t, buf.String(), `"msg":"async"`)
+
+	assert.Equal(t, ErrorLevel, NewSlogLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})).GetLevel())
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"io"
+	stdlog "log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stdLogCaller file:line prefix written by the standard logger with the Lshortfile or Llongfile flag
+var stdLogCaller = regexp.MustCompile(`^(\S+\.go:\d+): `)
+
+// stdLogMu guards the standard logger settings while they are replaced or restored
+var stdLogMu sync.Mutex
+
+// stdLogWriter writes the lines of a standard logger with a logger
+type stdLogWriter struct {
+	log   CommonLog
+	level logrus.Level
+}
+
+// NewStdLogWriter returns a writer for a standard logger (e.g. http.Server.ErrorLog) turning every line
+// into an entry of the logger at the level, with the context fields of the logger when the line is written.
+// The file:line prefix of the Lshortfile and Llongfile flags is the file field. The fatal and panic levels
+// are written as errors, the standard logger exits or panics itself.
+func NewStdLogWriter(l CommonLog, level LevelLog) io.Writer {
+	lvl, err := toLogrusLevel(level)
+	if err != nil {
+		lvl = logrus.InfoLevel
+	}
+
+	if lvl < logrus.ErrorLevel {
+		lvl = logrus.ErrorLevel
+	}
+
+	return &stdLogWriter{log: l, level: lvl}
+}
+
+// Write writes the line
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	fields := logrus.Fields{}
+
+	if m := stdLogCaller.FindStringSubmatch(msg); m != nil {
+		fields[logrus.FieldKeyFile] = m[1]
+		msg = msg[len(m[0]):]
+	}
+
+	w.log.WithFields(fields).Log(w.level, msg)
+
+	return len(p), nil
+}
+
+// RedirectStdLog writes the lines of the standard logger with the logger (see NewStdLogWriter), its flags
+// are set to Lshortfile and its prefix removed. The returned function restores the output, flags and prefix.
+func RedirectStdLog(l CommonLog, level LevelLog) (restore func()) {
+	stdLogMu.Lock()
+	defer stdLogMu.Unlock()
+
+	out, flags, prefix := stdlog.Writer(), stdlog.Flags(), stdlog.Prefix()
+
+	stdlog.SetOutput(NewStdLogWriter(l, level))
+	stdlog.SetFlags(stdlog.Lshortfile)
+	stdlog.SetPrefix("")
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			stdLogMu.Lock()
+			defer stdLogMu.Unlock()
+
+			stdlog.SetOutput(out)
+			stdlog.SetFlags(flags)
+			stdlog.SetPrefix(prefix)
+		})
+	}
+}
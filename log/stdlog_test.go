@@ -0,0 +1,71 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	var previous bytes.Buffer
+
+	stdlog.SetOutput(&previous)
+	stdlog.SetFlags(stdlog.LstdFlags)
+	stdlog.SetPrefix("lib: ")
+
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetComponent("webhook")
+
+	restore := RedirectStdLog(l, WarnLevel)
+
+	stdlog.Printf("connection reset")
+	l.SetOperation("create")
+	stdlog.Print("multi\nline")
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "warning", fields[0]["level"])
+		assert.Equal(t, "connection reset", fields[0]["msg"])
+		assert.Equal(t, "webhook", fields[0]["component"])
+		assert.Regexp(t, `^stdlog_test\.go:\d+$`, fields[0]["file"])
+		assert.NotContains(t, fields[0], "operation")
+
+		assert.Equal(t, "create", fields[1]["operation"])
+		assert.Equal(t, "multi\nline", fields[1]["msg"])
+	}
+
+	restore()
+	restore()
+
+	stdlog.Print("restored")
+	assert.Len(t, out.Lines(), 2)
+	assert.Contains(t, previous.String(), "lib: ")
+	assert.Contains(t, previous.String(), "restored")
+	assert.Equal(t, stdlog.LstdFlags, stdlog.Flags())
+
+	stdlog.SetOutput(os.Stderr)
+	stdlog.SetPrefix("")
+}
+
+func TestStdLogWriter(t *testing.T) {
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType)
+
+	stdlog.New(NewStdLogWriter(l, FatalLevel), "", stdlog.Llongfile).Print("not fatal")
+	stdlog.New(NewStdLogWriter(l, "unknown"), "", 0).Print("info")
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "error", fields[0]["level"])
+		assert.Regexp(t, `/log/stdlog_test\.go:\d+$`, fields[0]["file"])
+		assert.Equal(t, "info", fields[1]["level"])
+		assert.NotContains(t, fields[1], "file")
+	}
+}
@@ -0,0 +1,263 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSyslogSDID structured data id of the context fields, 32473 is the enterprise number reserved for examples
+	DefaultSyslogSDID = "goutils@32473"
+
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SyslogFraming framing of the messages on stream connections (RFC 6587)
+type SyslogFraming string
+
+var (
+	// OctetCountingFraming OctetCountingFraming
+	OctetCountingFraming SyslogFraming = "octet-counting"
+	// NonTransparentFraming NonTransparentFraming
+	NonTransparentFraming SyslogFraming = "non-transparent"
+)
+
+// syslogSDKeys context fields written in the structured data element
+var syslogSDKeys = []string{ClusterKey, ApplicationKey, ComponentKey, OperationKey}
+
+// SyslogConfig syslog output configuration
+type SyslogConfig struct {
+	// Network udp, tcp, tls, unix or unixgram
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// Address host:port, or the socket path for unix and unixgram
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Level most verbose level sent, the level of the logger when empty
+	Level LevelLog `json:"level,omitempty" yaml:"level,omitempty"`
+	// Framing stream framing, octet counting when empty (not used by udp and unixgram).
+	// The new lines of the messages are replaced with spaces with the non transparent framing.
+	Framing SyslogFraming `json:"framing,omitempty" yaml:"framing,omitempty"`
+	// Facility syslog facility, 1 (user) when 0
+	Facility int `json:"facility,omitempty" yaml:"facility,omitempty"`
+	// AppName APP-NAME header, the process name when empty
+	AppName string `json:"appName,omitempty" yaml:"appName,omitempty"`
+	// Hostname HOSTNAME header, the hostname when empty
+	Hostname string `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	// SDID structured data id of the context fields, DefaultSyslogSDID when empty
+	SDID string `json:"sdId,omitempty" yaml:"sdId,omitempty"`
+	// TLSConfig tls configuration of the tls network, can only be set in code
+	TLSConfig *tls.Config `json:"-" yaml:"-"`
+}
+
+// SyslogFormatter formats entries as RFC 5424 messages, the cluster, app, component and operation fields
+// are written in a structured data element and the other fields after the message as logfmt
+type SyslogFormatter struct {
+	// Facility syslog facility
+	Facility int
+	// AppName APP-NAME header, the process name when empty
+	AppName string
+	// Hostname HOSTNAME header, the hostname when empty
+	Hostname string
+	// SDID structured data id, DefaultSyslogSDID when empty
+	SDID string
+}
+
+// Format formats the entry without framing
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := &bytes.Buffer{}
+
+	hostname := f.Hostname
+	if hostname == "" {
+		hostname = defaultHostname()
+	}
+
+	appName := f.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+
+	sdID := f.SDID
+	if sdID == "" {
+		sdID = DefaultSyslogSDID
+	}
+
+	fmt.Fprintf(b, "<%d>1 %s %s %s %d - ", f.Facility*8+syslogSeverity(entry.Level),
+		entry.Time.Format(syslogTimeFormat), syslogHeader(hostname, 255), syslogHeader(appName, 48), os.Getpid())
+
+	sd := &bytes.Buffer{}
+
+	for _, key := range syslogSDKeys {
+		if v, ok := entry.Data[key]; ok {
+			fmt.Fprintf(sd, " %s=\"%s\"", key, syslogParamValue(fmt.Sprint(v)))
+		}
+	}
+
+	if sd.Len() == 0 {
+		b.WriteString("-")
+	} else {
+		fmt.Fprintf(b, "[%s%s]", sdID, sd.String())
+	}
+
+	msg := &bytes.Buffer{}
+	msg.WriteString(entry.Message)
+
+	for _, key := range orderedKeys(entry.Data) {
+		if !isSyslogSDKey(key) {
+			appendLogfmt(msg, key, entry.Data[key])
+		}
+	}
+
+	if msg.Len() > 0 {
+		b.WriteByte(' ')
+		b.Write(msg.Bytes())
+	}
+
+	return b.Bytes(), nil
+}
+
+func isSyslogSDKey(key string) bool {
+	for _, k := range syslogSDKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syslogHeader returns the header field printable, without spaces and truncated
+func syslogHeader(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	if len(s) > max {
+		s = s[:max]
+	}
+
+	return s
+}
+
+// syslogParamValue escapes the ", \ and ] of a structured data parameter value
+func syslogParamValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// NewSyslogSink creates a sink sending RFC 5424 messages to a syslog server (see SyslogConfig),
+// the connection is opened on the first entry and opened again after a failure
+func NewSyslogSink(name string, cfg SyslogConfig) (Sink, error) {
+	writer, err := newSyslogWriter(cfg)
+	if err != nil {
+		return Sink{}, err
+	}
+
+	return Sink{Name: name, Writer: writer, Level: cfg.Level}, nil
+}
+
+// syslogWriter sends every write as a syslog message, framed on stream connections
+type syslogWriter struct {
+	mu        sync.Mutex
+	framing   SyslogFraming
+	stream    bool
+	conn      *connWriter
+	formatter *SyslogFormatter
+}
+
+func newSyslogWriter(cfg SyslogConfig) (*syslogWriter, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("invalid syslog config: empty address")
+	}
+
+	if cfg.Framing == "" {
+		cfg.Framing = OctetCountingFraming
+	}
+
+	if cfg.Framing != OctetCountingFraming && cfg.Framing != NonTransparentFraming {
+		return nil, fmt.Errorf("invalid syslog framing %q", cfg.Framing)
+	}
+
+	if cfg.Facility == 0 {
+		cfg.Facility = 1
+	}
+
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d", cfg.Facility)
+	}
+
+	var dial func(timeout time.Duration) (net.Conn, error)
+
+	switch cfg.Network {
+	case "udp", "tcp", "unix", "unixgram":
+		dial = func(timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout(cfg.Network, cfg.Address, timeout)
+		}
+	case "tls":
+		dial = func(timeout time.Duration) (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", cfg.Address, cfg.TLSConfig)
+		}
+	default:
+		return nil, fmt.Errorf("invalid syslog network %q: expected udp, tcp, tls, unix or unixgram", cfg.Network)
+	}
+
+	return &syslogWriter{
+		framing: cfg.Framing,
+		stream:  cfg.Network != "udp" && cfg.Network != "unixgram",
+		conn:    newConnWriter(dial),
+		formatter: &SyslogFormatter{
+			Facility: cfg.Facility, AppName: cfg.AppName, Hostname: cfg.Hostname, SDID: cfg.SDID,
+		},
+	}, nil
+}
+
+// Write sends the message framed for the connection
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+
+	if w.stream {
+		if w.framing == OctetCountingFraming {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		} else {
+			// the new lines of the message would be read as trailers
+			msg = append(bytes.Replace(msg, []byte("\n"), []byte(" "), -1), '\n')
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.conn.write(msg); err != nil {
+		return 0, fmt.Errorf("syslog: %v", err)
+	}
+
+	return len(p), nil
+}
+
+// Close closes the connection
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.conn.close()
+}
+
+// sinkFormatter formats the entries with the syslog formatter whatever the sink format
+func (w *syslogWriter) sinkFormatter() logrus.Formatter {
+	return w.formatter
+}
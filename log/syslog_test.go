@@ -0,0 +1,289 @@
+package log
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogFormatter(t *testing.T) {
+	ts := time.Date(2019, 8, 1, 15, 4, 5, 123456789, time.UTC)
+	header := fmt.Sprintf("1 2019-08-01T15:04:05.123456Z node1 estore %d - ", os.Getpid())
+
+	tests := []struct {
+		name    string
+		level   logrus.Level
+		message string
+		fields  logrus.Fields
+		want    string
+	}{
+		{
+			name: "no fields", level: logrus.InfoLevel, message: "test",
+			want: "<134>" + header + "- test",
+		},
+		{
+			name: "structured data", level: logrus.ErrorLevel, message: "test",
+			fields: logrus.Fields{
+				ClusterKey: "minikube", ApplicationKey: "estore", ComponentKey: `we"b]ho\ok`, OperationKey: "create",
+				UserKey: "johnny", "responseCode": 500,
+			},
+			want: "<131>" + header + `[goutils@32473 cluster="minikube" app="estore" component="we\"b\]ho\\ok" ` +
+				`operation="create"] test user=johnny responseCode=500`,
+		},
+		{
+			name: "no message", level: logrus.WarnLevel,
+			fields: logrus.Fields{OperationKey: "delete"},
+			want:   "<132>" + header + `[goutils@32473 operation="delete"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields).WithTime(ts)
+			entry.Level = tt.level
+			entry.Message = tt.message
+
+			got, err := (&SyslogFormatter{Facility: 16, AppName: "estore", Hostname: "node1"}).Format(entry)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestSyslogHeader(t *testing.T) {
+	assert.Equal(t, "-", syslogHeader("", 10))
+	assert.Equal(t, "my_app_", syslogHeader("my app\n", 10))
+	assert.Equal(t, "abc", syslogHeader("abcdef", 3))
+}
+
+func TestNewSyslogSinkInvalid(t *testing.T) {
+	for _, cfg := range []SyslogConfig{
+		{Network: "http", Address: "localhost:514"},
+		{Network: "udp"},
+		{Network: "tcp", Address: "localhost:514", Framing: "line"},
+		{Network: "tcp", Address: "localhost:514", Facility: 24},
+	} {
+		_, err := NewSyslogSink("syslog", cfg)
+		assert.NotNil(t, err, "%+v", cfg)
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	logger := newSyslogLogger(t, SyslogConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	defer logger.RemoveSink("syslog")
+
+	logger.SetCluster("minikube").Warn("test")
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<12>1 "), msg)
+	assert.True(t, strings.HasSuffix(msg, `[goutils@32473 cluster="minikube"] test`), msg)
+}
+
+func TestSyslogSinkStream(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "syslog")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		network string
+		address string
+		framing SyslogFraming
+		second  string
+	}{
+		{name: "tcp octet counting", network: "tcp", address: "127.0.0.1:0", second: "second\nline"},
+		{
+			name: "tcp non transparent", network: "tcp", address: "127.0.0.1:0", framing: NonTransparentFraming,
+			second: "second line",
+		},
+		{name: "unix octet counting", network: "unix", address: filepath.Join(dir, "syslog.sock"), second: "second\nline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen(tt.network, tt.address)
+			assert.Nil(t, err)
+			defer ln.Close()
+
+			messages := readSyslogStream(ln, tt.framing)
+
+			logger := newSyslogLogger(t, SyslogConfig{Network: tt.network, Address: ln.Addr().String(), Framing: tt.framing})
+			defer logger.RemoveSink("syslog")
+
+			logger.SetComponent("webhook").Info("first")
+			logger.Info("second\nline")
+
+			assert.True(t, strings.HasSuffix(<-messages, `[goutils@32473 component="webhook"] first`))
+			assert.True(t, strings.HasSuffix(<-messages, `[goutils@32473 component="webhook"] `+tt.second))
+		})
+	}
+}
+
+func TestSyslogSinkTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.StartTLS()
+	srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	messages := readSyslogStream(ln, OctetCountingFraming)
+
+	logger := newSyslogLogger(t, SyslogConfig{
+		Network: "tls", Address: ln.Addr().String(),
+		TLSConfig: srv.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	defer logger.RemoveSink("syslog")
+
+	logger.SetOperation("create").Error("test")
+
+	assert.True(t, strings.HasSuffix(<-messages, `[goutils@32473 operation="create"] test`))
+}
+
+func TestSyslogSinkReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+
+	go func() {
+		// the first connection is closed after one message
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			for {
+				msg, err := readOctetCounted(r)
+				if err != nil {
+					break
+				}
+
+				messages <- msg
+
+				if i == 0 {
+					break
+				}
+			}
+
+			conn.Close()
+		}
+	}()
+
+	logger := newSyslogLogger(t, SyslogConfig{Network: "tcp", Address: ln.Addr().String()})
+	defer logger.RemoveSink("syslog")
+
+	logger.Info("first")
+	assert.True(t, strings.HasSuffix(<-messages, "- first"))
+
+	// writes to the closed connection may succeed once before failing
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		logger.Info("second")
+
+		select {
+		case msg := <-messages:
+			assert.True(t, strings.HasSuffix(msg, "- second"))
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("no message received after reconnect")
+}
+
+func newSyslogLogger(t *testing.T, cfg SyslogConfig) *Log {
+	cfg.AppName = "estore"
+
+	sink, err := NewSyslogSink("syslog", cfg)
+	assert.Nil(t, err)
+
+	logger := newLogger()
+	logger.logger.Out = ioutil.Discard
+	assert.Nil(t, logger.AddSink(sink))
+
+	return logger
+}
+
+// readSyslogStream reads the messages of the first connection
+func readSyslogStream(ln net.Listener, framing SyslogFraming) <-chan string {
+	messages := make(chan string, 10)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+
+		for {
+			var (
+				msg string
+				err error
+			)
+
+			if framing == NonTransparentFraming {
+				msg, err = r.ReadString('\n')
+				msg = strings.TrimSuffix(msg, "\n")
+			} else {
+				msg, err = readOctetCounted(r)
+			}
+
+			if err != nil {
+				return
+			}
+
+			messages <- msg
+		}
+	}()
+
+	return messages
+}
+
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+
+	return string(msg), nil
+}
@@ -0,0 +1,196 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// trace field keys
+const (
+	// TraceIDKey TraceIDKey
+	TraceIDKey = "trace_id"
+	// SpanIDKey SpanIDKey
+	SpanIDKey = "span_id"
+)
+
+// TraceparentHeader W3C trace context header, also the gRPC metadata key
+const TraceparentHeader = "traceparent"
+
+// traceparent version written, the sampled flag
+const (
+	traceVersion = "00"
+	sampledFlag  = 0x01
+)
+
+// TraceContext W3C trace context (https://www.w3.org/TR/trace-context/) of a span: 32 and 16 lower case
+// hex digits ids
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// TraceExtractor returns the trace context of the span of the context, OpenTelemetry for instance (see logotel)
+type TraceExtractor func(ctx context.Context) (TraceContext, bool)
+
+var (
+	traceExtractor   TraceExtractor
+	traceExtractorMu sync.RWMutex
+)
+
+// SetTraceExtractor sets the extractor used by TraceFromContext before the trace stored with ContextWithTrace,
+// nil removes it
+func SetTraceExtractor(e TraceExtractor) {
+	traceExtractorMu.Lock()
+	defer traceExtractorMu.Unlock()
+
+	traceExtractor = e
+}
+
+// NewTraceContext returns a sampled trace context with random trace and span ids
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Sampled: true}
+}
+
+// ParseTraceparent parses the traceparent header value, the versions after 00 are parsed like 00
+func ParseTraceparent(s string) (TraceContext, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.SplitN(s, "-", 5)
+
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	version, flags := parts[0], parts[3]
+
+	switch {
+	case !isHex(version, 2) || version == "ff":
+		return TraceContext{}, fmt.Errorf("invalid traceparent version %q", version)
+	case version == traceVersion && len(parts) > 4:
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", s)
+	case !isHex(flags, 2):
+		return TraceContext{}, fmt.Errorf("invalid traceparent flags %q", flags)
+	}
+
+	t := TraceContext{TraceID: parts[1], SpanID: parts[2]}
+	if !t.IsValid() {
+		return TraceContext{}, fmt.Errorf("invalid traceparent ids %q", s)
+	}
+
+	b, _ := hex.DecodeString(flags)
+	t.Sampled = b[0]&sampledFlag != 0
+
+	return t, nil
+}
+
+// IsValid checks the ids are lower case hex and not all zeros
+func (t TraceContext) IsValid() bool {
+	return isHex(t.TraceID, 32) && isHex(t.SpanID, 16) &&
+		strings.Trim(t.TraceID, "0") != "" && strings.Trim(t.SpanID, "0") != ""
+}
+
+// NewSpan returns the trace context of a child span: same trace, random span id
+func (t TraceContext) NewSpan() TraceContext {
+	t.SpanID = randomHex(8)
+	return t
+}
+
+// Traceparent returns the traceparent header value
+func (t TraceContext) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+
+	return traceVersion + "-" + t.TraceID + "-" + t.SpanID + "-" + flags
+}
+
+// ContextWithTrace returns a copy of ctx carrying the trace context, set by Middleware and sent by AuditTransport
+func ContextWithTrace(ctx context.Context, t TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey, t)
+}
+
+// TraceFromContext returns the trace context of the span of the context (see SetTraceExtractor) or the one
+// stored with ContextWithTrace
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	traceExtractorMu.RLock()
+	extract := traceExtractor
+	traceExtractorMu.RUnlock()
+
+	if extract != nil {
+		if t, ok := extract(ctx); ok && t.IsValid() {
+			return t, true
+		}
+	}
+
+	t, ok := ctx.Value(traceContextKey).(TraceContext)
+
+	return t, ok && t.IsValid()
+}
+
+// SetTrace sets the trace id and span id fields, an invalid trace context removes them
+func (l *Log) SetTrace(t TraceContext) *Log {
+	if !t.IsValid() {
+		return l.RemoveField(TraceIDKey).RemoveField(SpanIDKey)
+	}
+
+	return l.SetField(TraceIDKey, t.TraceID).SetField(SpanIDKey, t.SpanID)
+}
+
+// WithTrace returns a ThreadLogger with the trace id and span id fields
+func (l *Log) WithTrace(t TraceContext) *Log {
+	return l.ThreadLogger().SetTrace(t)
+}
+
+// ServerTrace returns the trace context of a server call: the one of the span of the context, a child of the
+// parent traceparent received or a new one
+func ServerTrace(ctx context.Context, traceparent string) TraceContext {
+	if t, ok := TraceFromContext(ctx); ok {
+		return t
+	}
+
+	if parent, err := ParseTraceparent(traceparent); err == nil {
+		return parent.NewSpan()
+	}
+
+	return NewTraceContext()
+}
+
+// ClientTrace returns the trace context of a client call: the one of the traceparent already sent, a child of
+// the span of the context or a new one
+func ClientTrace(ctx context.Context, traceparent string) TraceContext {
+	if t, err := ParseTraceparent(traceparent); err == nil {
+		return t
+	}
+
+	if t, ok := TraceFromContext(ctx); ok {
+		return t.NewSpan()
+	}
+
+	return NewTraceContext()
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
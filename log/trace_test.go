@@ -0,0 +1,188 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID      = "00f067aa0ba902b7"
+	testTraceparent = "00-" + testTraceID + "-" + testSpanID + "-01"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value   string
+		trace   TraceContext
+		wantErr bool
+	}{
+		{testTraceparent, TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true}, false},
+		{" 00-" + testTraceID + "-" + testSpanID + "-00 ", TraceContext{TraceID: testTraceID, SpanID: testSpanID}, false},
+		{"01-" + testTraceID + "-" + testSpanID + "-03-future", TraceContext{
+			TraceID: testTraceID, SpanID: testSpanID, Sampled: true,
+		}, false},
+		{"", TraceContext{}, true},
+		{"00-" + testTraceID + "-" + testSpanID, TraceContext{}, true},
+		{"00-" + testTraceID + "-" + testSpanID + "-01-extra", TraceContext{}, true},
+		{"ff-" + testTraceID + "-" + testSpanID + "-01", TraceContext{}, true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01", TraceContext{}, true},
+		{"00-00000000000000000000000000000000-" + testSpanID + "-01", TraceContext{}, true},
+		{"00-" + testTraceID + "-0000000000000000-01", TraceContext{}, true},
+		{"00-" + testTraceID + "-" + testSpanID + "-1", TraceContext{}, true},
+	}
+
+	for _, tt := range tests {
+		trace, err := ParseTraceparent(tt.value)
+		assert.Equal(t, tt.wantErr, err != nil, tt.value)
+		assert.Equal(t, tt.trace, trace, tt.value)
+	}
+}
+
+func TestNewTraceContext(t *testing.T) {
+	trace := NewTraceContext()
+	assert.True(t, trace.IsValid())
+	assert.True(t, trace.Sampled)
+
+	parsed, err := ParseTraceparent(trace.Traceparent())
+	assert.NoError(t, err)
+	assert.Equal(t, trace, parsed)
+
+	span := trace.NewSpan()
+	assert.Equal(t, trace.TraceID, span.TraceID)
+	assert.NotEqual(t, trace.SpanID, span.SpanID)
+	assert.True(t, span.IsValid())
+
+	assert.Equal(t, "00-"+testTraceID+"-"+testSpanID+"-00", TraceContext{TraceID: testTraceID, SpanID: testSpanID}.Traceparent())
+}
+
+func TestSetTrace(t *testing.T) {
+	l := newLogger()
+	trace := TraceContext{TraceID: testTraceID, SpanID: testSpanID}
+
+	l.SetTrace(trace)
+
+	// the fields are propagated to the thread loggers and kept by push/pop
+	tl := l.ThreadLogger()
+	tl.PushContext()
+	tl.SetTrace(trace.NewSpan())
+	logAndAssertJSON(t, tl, "test", func(fields logrus.Fields) {
+		assert.Equal(t, testTraceID, fields[TraceIDKey])
+		assert.NotEqual(t, testSpanID, fields[SpanIDKey])
+	})
+	tl.PopContext()
+
+	logAndAssertJSON(t, tl, "test", func(fields logrus.Fields) {
+		assert.Equal(t, testTraceID, fields[TraceIDKey])
+		assert.Equal(t, testSpanID, fields[SpanIDKey])
+	})
+
+	logAndAssertJSON(t, l.WithTrace(TraceContext{}), "test", func(fields logrus.Fields) {
+		assert.Nil(t, fields[TraceIDKey])
+		assert.Nil(t, fields[SpanIDKey])
+	})
+
+	logAndAssertJSON(t, l, "test", func(fields logrus.Fields) {
+		assert.Equal(t, testSpanID, fields[SpanIDKey])
+	})
+}
+
+func TestTraceFromContext(t *testing.T) {
+	defer SetTraceExtractor(nil)
+
+	trace := TraceContext{TraceID: testTraceID, SpanID: testSpanID}
+
+	_, ok := TraceFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = TraceFromContext(ContextWithTrace(context.Background(), TraceContext{TraceID: testTraceID}))
+	assert.False(t, ok)
+
+	ctx := ContextWithTrace(IntoContext(context.Background(), newLogger()), trace)
+
+	got, ok := TraceFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, trace, got)
+
+	logAndAssertJSON(t, FromContext(ctx).(*Log), "test", func(fields logrus.Fields) {
+		assert.Equal(t, testTraceID, fields[TraceIDKey])
+		assert.Equal(t, testSpanID, fields[SpanIDKey])
+	})
+
+	// the span of the extractor comes first, the stored trace is used when it has none
+	span := trace.NewSpan()
+	SetTraceExtractor(func(ctx context.Context) (TraceContext, bool) {
+		return span, ctx.Value(loggerContextKey) != nil
+	})
+
+	got, _ = TraceFromContext(ctx)
+	assert.Equal(t, span, got)
+
+	got, _ = TraceFromContext(ContextWithTrace(context.Background(), trace))
+	assert.Equal(t, trace, got)
+}
+
+func TestClientServerTrace(t *testing.T) {
+	parent := TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true}
+
+	server := ServerTrace(context.Background(), testTraceparent)
+	assert.Equal(t, testTraceID, server.TraceID)
+	assert.NotEqual(t, testSpanID, server.SpanID)
+
+	assert.Equal(t, parent, ServerTrace(ContextWithTrace(context.Background(), parent), ""))
+	assert.NotEqual(t, testTraceID, ServerTrace(context.Background(), "invalid").TraceID)
+
+	assert.Equal(t, parent, ClientTrace(context.Background(), testTraceparent))
+
+	client := ClientTrace(ContextWithTrace(context.Background(), parent), "")
+	assert.Equal(t, testTraceID, client.TraceID)
+	assert.NotEqual(t, testSpanID, client.SpanID)
+
+	assert.True(t, ClientTrace(context.Background(), "").IsValid())
+}
+
+func TestMiddlewareTransportTrace(t *testing.T) {
+	var traceparents []string
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get(TraceparentHeader))
+	}))
+	defer backend.Close()
+
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel)
+
+	client := &http.Client{Transport: AuditTransport(nil, l, HTTPConfig{})}
+
+	// the middleware continues the trace of the caller, the transport sends it to the backend
+	handler := Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		if resp, err := client.Do(req); assert.NoError(t, err) {
+			assert.NoError(t, resp.Body.Close())
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(TraceparentHeader, testTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 2) && assert.Len(t, traceparents, 1) {
+		for _, f := range fields {
+			assert.Equal(t, testTraceID, f[TraceIDKey])
+			assert.NotEqual(t, testSpanID, f[SpanIDKey])
+		}
+
+		// transport span, child of the middleware span
+		assert.Equal(t, backend.URL, fields[0]["endpoint"])
+		assert.NotEqual(t, fields[1][SpanIDKey], fields[0][SpanIDKey])
+		assert.Equal(t, "00-"+testTraceID+"-"+fields[0][SpanIDKey].(string)+"-01", traceparents[0])
+	}
+}
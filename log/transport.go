@@ -0,0 +1,138 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// auditTransport round tripper logging the calls
+type auditTransport struct {
+	base http.RoundTripper
+	log  CommonLog
+	cfg  HTTPConfig
+}
+
+// AuditTransport returns a round tripper (http.DefaultTransport when base is nil) logging every call with
+// an audit line like LogAuditAPI: method, url, size limited and redacted bodies, status code, latency,
+// response bytes and error. The request id header is sent with the request id of the request, of its context
+// (see ContextWithRequestID, set by Middleware) or a generated one, and logged as the request id field.
+// The traceparent header is sent and logged with the trace and span ids (see ClientTrace).
+// The line is written when the response body is read or closed, or when the call fails.
+func AuditTransport(base http.RoundTripper, l CommonLog, cfg HTTPConfig) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &auditTransport{base: base, log: l, cfg: cfg.withDefaults()}
+}
+
+// RoundTrip sends the request and logs the call
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	call := &auditCall{transport: t, req: req, start: time.Now(), reqBody: newBodyBuffer(t.cfg.MaxBodySize)}
+
+	id := req.Header.Get(t.cfg.RequestIDHeader)
+	if id == "" {
+		id = RequestIDFromContext(req.Context())
+	}
+
+	if id == "" {
+		id = NewRequestID()
+	}
+
+	call.id = id
+	call.trace = ClientTrace(req.Context(), req.Header.Get(TraceparentHeader))
+
+	// the request can not be changed
+	r := req.Clone(req.Context())
+	r.Header.Set(t.cfg.RequestIDHeader, id)
+	r.Header.Set(TraceparentHeader, call.trace.Traceparent())
+
+	if req.Body != nil && req.Body != http.NoBody {
+		r.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, call.reqBody), Closer: req.Body}
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		call.done(nil, nil, err)
+		return nil, err
+	}
+
+	resp.Body = &auditBody{ReadCloser: resp.Body, call: call, resp: resp, body: newBodyBuffer(t.cfg.MaxBodySize)}
+
+	return resp, nil
+}
+
+// auditCall call logged by the transport
+type auditCall struct {
+	transport *auditTransport
+	req       *http.Request
+	id        string
+	trace     TraceContext
+	start     time.Time
+	reqBody   *bodyBuffer
+	once      sync.Once
+}
+
+// done writes the audit line once
+func (c *auditCall) done(resp *http.Response, body *bodyBuffer, err error) {
+	c.once.Do(func() {
+		var (
+			cfg      = c.transport.cfg
+			response string
+			code     int
+		)
+
+		if resp != nil {
+			response, code = cfg.body(body), resp.StatusCode
+		}
+
+		fields := AuditAPIFields(c.req.Method, cfg.endpoint(c.req.URL).Redacted(), cfg.body(c.reqBody), response, code)
+		fields[RequestIDKey] = c.id
+		fields[TraceIDKey] = c.trace.TraceID
+		fields[SpanIDKey] = c.trace.SpanID
+		fields[LatencyKey] = time.Since(c.start).String()
+
+		if body != nil {
+			_, fields[BytesKey], _ = body.contents()
+		}
+
+		entry := c.transport.log.WithFields(fields)
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+
+		entry.Log(logrusLevels[cfg.Level], "audit api")
+	})
+}
+
+// auditBody response body logging the call when read or closed
+type auditBody struct {
+	io.ReadCloser
+	call *auditCall
+	resp *http.Response
+	body *bodyBuffer
+}
+
+// Read reads the body, the call is logged at the end of the body
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.body.Write(p[:n])
+
+	if err == io.EOF {
+		b.call.done(b.resp, b.body, nil)
+	} else if err != nil {
+		b.call.done(b.resp, b.body, err)
+	}
+
+	return n, err
+}
+
+// Close closes the body and logs the call
+func (b *auditBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.call.done(b.resp, b.body, nil)
+
+	return err
+}
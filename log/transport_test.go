@@ -0,0 +1,129 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditTransport(t *testing.T) {
+	var ids []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(RequestIDHeader))
+		body, _ := ioutil.ReadAll(r.Body)
+
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"echo":` + string(body) + `,"token":"t1"}`))
+	}))
+	defer server.Close()
+
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType).SetLevel(DebugLevel).SetComponent("client")
+
+	client := &http.Client{Transport: AuditTransport(nil, l, HTTPConfig{})}
+
+	resp, err := client.Post(server.URL+"/orders", "application/json", strings.NewReader(`{"password":"secret1"}`))
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, `{"echo":{"password":"secret1"},"token":"t1"}`, string(body))
+		assert.NoError(t, resp.Body.Close())
+	}
+
+	// request id of the request and of the context, credentials of the url
+	endpoint := strings.Replace(server.URL, "//", "//johnny:secret1@", 1) + "/orders/1?token=t1&v=2"
+	req, _ := http.NewRequest(http.MethodGet, endpoint, nil)
+	req.Header.Set(RequestIDHeader, "r1")
+
+	if resp, err := client.Do(req); assert.NoError(t, err) {
+		assert.NoError(t, resp.Body.Close())
+	}
+
+	req, _ = http.NewRequestWithContext(ContextWithRequestID(context.Background(), "r2"), http.MethodGet, server.URL, nil)
+	if resp, err := client.Do(req); assert.NoError(t, err) {
+		assert.NoError(t, resp.Body.Close())
+	}
+
+	assert.Empty(t, req.Header.Get(RequestIDHeader))
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 3) && assert.Len(t, ids, 3) {
+		assert.Len(t, ids[0], 32)
+		assert.Equal(t, []string{"r1", "r2"}, ids[1:])
+
+		assert.Equal(t, "audit api", fields[0]["msg"])
+		assert.Equal(t, "client", fields[0]["component"])
+		assert.Equal(t, "api", fields[0]["auditType"])
+		assert.Equal(t, "POST", fields[0]["httpType"])
+		assert.Equal(t, server.URL+"/orders", fields[0]["endpoint"])
+		assert.Equal(t, `{"password":"[REDACTED]"}`, fields[0]["request"])
+		assert.Equal(t, `{"echo":{"password":"[REDACTED]"},"token":"[REDACTED]"}`, fields[0]["response"])
+		assert.Equal(t, float64(202), fields[0]["responseCode"])
+		assert.Equal(t, float64(44), fields[0][BytesKey])
+		assert.Equal(t, ids[0], fields[0][RequestIDKey])
+		assert.NotEmpty(t, fields[0][LatencyKey])
+
+		assert.Equal(t, "r1", fields[1][RequestIDKey])
+		assert.Equal(t, strings.Replace(server.URL, "//", "//johnny:xxxxx@", 1)+"/orders/1?token=[REDACTED]&v=2",
+			fields[1]["endpoint"])
+		assert.Equal(t, "", fields[1]["response"])
+		assert.Equal(t, float64(0), fields[1][BytesKey])
+		assert.Equal(t, "r2", fields[2][RequestIDKey])
+	}
+}
+
+func TestAuditTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	l := newLogger()
+	out := &MemoryWriter{}
+	l.logger.Out = out
+	l.SetFormatterType(JSONFormatterType)
+
+	client := &http.Client{Transport: AuditTransport(http.DefaultTransport, l, HTTPConfig{Level: ErrorLevel})}
+
+	_, err := client.Get(server.URL)
+	assert.Error(t, err)
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "error", fields[0]["level"])
+		assert.Equal(t, float64(0), fields[0]["responseCode"])
+		assert.Contains(t, fields[0]["error"], "connection refused")
+		assert.NotContains(t, fields[0], BytesKey)
+	}
+}
+
+func TestMiddlewareAuditTransport(t *testing.T) {
+	var id string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get(RequestIDHeader)
+	}))
+	defer server.Close()
+
+	l := newLogger()
+	l.logger.Out = ioutil.Discard
+	client := &http.Client{Transport: AuditTransport(nil, l, HTTPConfig{})}
+
+	handler := Middleware(l, HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, server.URL, nil)
+		if resp, err := client.Do(req); assert.NoError(t, err) {
+			_ = resp.Body.Close()
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "r1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "r1", id)
+}
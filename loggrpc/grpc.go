@@ -0,0 +1,404 @@
+// Package loggrpc gRPC interceptors logging the calls with a log.CommonLog
+package loggrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/arutselvan15/go-utils/redact"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// call field keys
+const (
+	// PeerKey PeerKey
+	PeerKey = "peer"
+	// CodeKey CodeKey
+	CodeKey = "grpcCode"
+	// DirectionKey DirectionKey
+	DirectionKey = "direction"
+	// CountKey CountKey
+	CountKey = "count"
+	// PayloadKey PayloadKey
+	PayloadKey = "payload"
+)
+
+// UserMetadataKey default metadata key of the user
+const UserMetadataKey = "user"
+
+// Config configuration of the interceptors
+type Config struct {
+	// User returns the user of the incoming metadata, the user metadata when nil (server only)
+	User func(md metadata.MD) string
+	// RequestIDKey metadata key of the request id, log.RequestIDHeader in lower case when empty
+	RequestIDKey string
+	// Level level of the audit entry, debug (like LogAuditAPI) when empty
+	Level log.LevelLog
+	// Messages logs the messages of the streams at trace level when set
+	Messages *MessageSampling
+	// Redactor redacts the messages, redact.Default() when nil
+	Redactor *redact.Redactor
+
+	level logrus.Level
+}
+
+// MessageSampling logs the First messages of each direction of a stream, then every Thereafter-th message,
+// every message when both are 0
+type MessageSampling struct {
+	First      int
+	Thereafter int
+}
+
+// UnaryServerInterceptor returns an interceptor logging every call with a ThreadLogger of the logger.
+// The logger has the full method as operation, the peer, the user, the request id (read from the metadata
+// or generated, and sent in the header) and the trace (see log.ServerTrace with the traceparent metadata)
+// fields, it is stored in the context for the handler (see log.FromContext). When the handler returns, an
+// "api" audit entry is written with the status code, latency and redacted request and response.
+func UnaryServerInterceptor(l log.CommonLog, cfg Config) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		c := newServerCall(ctx, l, cfg, info.FullMethod)
+
+		resp, err := handler(c.ctx, req)
+
+		response := ""
+		if err == nil {
+			response = cfg.message(resp)
+		}
+
+		c.done(cfg.message(req), response, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor logging every stream like UnaryServerInterceptor, the
+// messages are logged when Config.Messages is set
+func StreamServerInterceptor(l log.CommonLog, cfg Config) grpc.StreamServerInterceptor {
+	cfg = cfg.withDefaults()
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c := newServerCall(ss.Context(), l, cfg, info.FullMethod)
+
+		err := handler(srv, &serverStream{ServerStream: ss, call: c})
+		c.done("", "", err)
+
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor logging every call with a ThreadLogger of the logger.
+// The request id of the outgoing metadata, of the context (see log.ContextWithRequestID) or a generated one
+// and the traceparent (see log.ClientTrace) are sent in the metadata. When the call returns, an "api" audit
+// entry is written with the full method as operation, the peer, request id, trace, status code, latency and
+// redacted request and response.
+func UnaryClientInterceptor(l log.CommonLog, cfg Config) grpc.UnaryClientInterceptor {
+	cfg = cfg.withDefaults()
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := newClientCall(ctx, l, cfg, method)
+
+		err := invoker(c.ctx, method, req, reply, cc, append(opts, grpc.Peer(&c.peer))...)
+
+		response := ""
+		if err == nil {
+			response = cfg.message(reply)
+		}
+
+		c.done(cfg.message(req), response, err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor logging every stream like UnaryClientInterceptor, the
+// entry is written when the stream ends, the messages are logged when Config.Messages is set
+func StreamClientInterceptor(l log.CommonLog, cfg Config) grpc.StreamClientInterceptor {
+	cfg = cfg.withDefaults()
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c := newClientCall(ctx, l, cfg, method)
+
+		cs, err := streamer(c.ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.done("", "", err)
+			return nil, err
+		}
+
+		return &clientStream{ClientStream: cs, call: c, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// withDefaults returns the config with the defaults set
+func (c Config) withDefaults() Config {
+	if c.User == nil {
+		c.User = metadataUser
+	}
+
+	if c.RequestIDKey == "" {
+		c.RequestIDKey = strings.ToLower(log.RequestIDHeader)
+	}
+
+	if c.Redactor == nil {
+		c.Redactor = redact.Default()
+	}
+
+	level, err := logrus.ParseLevel(string(c.Level))
+	if err != nil {
+		level = logrus.DebugLevel
+	}
+
+	c.level = level
+
+	return c
+}
+
+// message returns the redacted message, as json for the protobuf messages
+func (c Config) message(m interface{}) string {
+	if m == nil {
+		return ""
+	}
+
+	if pm, ok := m.(proto.Message); ok {
+		if b, err := protojson.Marshal(pm); err == nil {
+			return c.Redactor.Text(string(b))
+		}
+	}
+
+	return c.Redactor.String(fmt.Sprint(m))
+}
+
+// sampled checks the n-th message of a direction is logged
+func (s *MessageSampling) sampled(n int) bool {
+	if s.First == 0 && s.Thereafter == 0 {
+		return true
+	}
+
+	if n <= s.First {
+		return true
+	}
+
+	return s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0
+}
+
+func metadataUser(md metadata.MD) string {
+	return first(md.Get(UserMetadataKey))
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// call call logged by an interceptor
+type call struct {
+	cfg      Config
+	log      *log.Log
+	ctx      context.Context
+	method   string
+	start    time.Time
+	peer     peer.Peer
+	sent     int64
+	received int64
+	once     sync.Once
+}
+
+func newServerCall(ctx context.Context, l log.CommonLog, cfg Config, method string) *call {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	id := first(md.Get(cfg.RequestIDKey))
+	if id == "" {
+		id = log.NewRequestID()
+	}
+
+	trace := log.ServerTrace(ctx, first(md.Get(log.TraceparentHeader)))
+
+	c := &call{cfg: cfg, log: l.ThreadLogger(), method: method, start: time.Now()}
+	c.log.SetOperation(method).SetField(log.RequestIDKey, id).SetTrace(trace)
+
+	if p, ok := peer.FromContext(ctx); ok {
+		c.log.SetField(PeerKey, p.Addr.String())
+	}
+
+	if user := cfg.User(md); user != "" {
+		c.log.SetUser(user)
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(cfg.RequestIDKey, id))
+
+	c.ctx = log.ContextWithTrace(log.ContextWithRequestID(log.IntoContext(ctx, c.log), id), trace)
+
+	return c
+}
+
+func newClientCall(ctx context.Context, l log.CommonLog, cfg Config, method string) *call {
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	id := first(md.Get(cfg.RequestIDKey))
+	if id == "" {
+		if id = log.RequestIDFromContext(ctx); id == "" {
+			id = log.NewRequestID()
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, cfg.RequestIDKey, id)
+	}
+
+	traceparent := first(md.Get(log.TraceparentHeader))
+
+	trace := log.ClientTrace(ctx, traceparent)
+	if traceparent == "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, log.TraceparentHeader, trace.Traceparent())
+	}
+
+	c := &call{cfg: cfg, log: l.ThreadLogger(), ctx: ctx, method: method, start: time.Now()}
+	c.log.SetOperation(method).SetField(log.RequestIDKey, id).SetTrace(trace)
+
+	return c
+}
+
+// done writes the audit entry once, with the peer copied then
+func (c *call) done(request, response string, err error) {
+	c.once.Do(func() {
+		c.write(c.peer, request, response, err)
+	})
+}
+
+func (c *call) write(p peer.Peer, request, response string, err error) {
+	code := status.Code(err)
+
+	fields := log.AuditAPIFields("grpc", c.method, request, response, int(code))
+	fields[CodeKey] = code.String()
+	fields[log.LatencyKey] = time.Since(c.start).String()
+
+	if p.Addr != nil {
+		fields[PeerKey] = p.Addr.String()
+	}
+
+	entry := c.log.WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+
+	entry.Log(c.cfg.level, "audit api")
+}
+
+// logMessage counts the message of the direction and logs it when sampled, the directions can be used by
+// different goroutines
+func (c *call) logMessage(direction string, count *int64, m interface{}) {
+	n := int(atomic.AddInt64(count, 1))
+	if c.cfg.Messages == nil || !c.cfg.Messages.sampled(n) {
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		DirectionKey: direction, CountKey: n, PayloadKey: c.cfg.message(m),
+	}).Trace("stream message")
+}
+
+// serverStream server stream with the context of the call, logging the messages
+type serverStream struct {
+	grpc.ServerStream
+	call *call
+}
+
+// Context returns the context of the call
+func (s *serverStream) Context() context.Context {
+	return s.call.ctx
+}
+
+// SendMsg sends and logs the message
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.logMessage("sent", &s.call.sent, m)
+	}
+
+	return err
+}
+
+// RecvMsg receives and logs the message
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.logMessage("received", &s.call.received, m)
+	}
+
+	return err
+}
+
+// clientStream client stream logging the messages and the call when it ends
+type clientStream struct {
+	grpc.ClientStream
+	call          *call
+	serverStreams bool
+	peerOnce      sync.Once
+}
+
+// Header returns the header metadata, the peer is known then
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	s.setPeer()
+
+	return md, err
+}
+
+// setPeer sets the peer of the call from the stream context, read once Header or RecvMsg returned as the
+// context commits the stream (no retry after)
+func (s *clientStream) setPeer() {
+	s.peerOnce.Do(func() {
+		if p, ok := peer.FromContext(s.ClientStream.Context()); ok {
+			s.call.peer = *p
+		}
+	})
+}
+
+// SendMsg sends and logs the message
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.call.logMessage("sent", &s.call.sent, m)
+	}
+
+	return err
+}
+
+// RecvMsg receives and logs the message, the call is logged at the end of the stream
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.setPeer()
+
+	switch {
+	case err == io.EOF:
+		s.call.done("", "", nil)
+	case err != nil:
+		s.call.done("", "", err)
+	default:
+		s.call.logMessage("received", &s.call.received, m)
+
+		// a single response ends the stream
+		if !s.serverStreams {
+			s.call.done("", "", nil)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,250 @@
+package loggrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer logs with the logger of the context
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse,
+	error) {
+	log.FromContext(ctx).Info("check")
+
+	if req.Service != "" {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	log.FromContext(stream.Context()).Info("watch")
+
+	for i := 0; i < 5; i++ {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newLogger returns a json logger writing to the memory writer
+func newLogger() (log.CommonLog, *log.MemoryWriter) {
+	out := &log.MemoryWriter{}
+
+	l := log.NewLogger().SetFormatterType(log.JSONFormatterType).SetLevel(log.TraceLevel)
+	l.GetEntry().Logger.SetOutput(out)
+
+	return l, out
+}
+
+// jsonLines returns the fields of the json lines written
+func jsonLines(out *log.MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range out.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+// dial starts a bufconn server with the server interceptors and returns a client with the client interceptors
+func dial(t *testing.T, server, client log.CommonLog, cfg Config) (healthpb.HealthClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+
+	s := grpc.NewServer(grpc.UnaryInterceptor(UnaryServerInterceptor(server, cfg)),
+		grpc.StreamInterceptor(StreamServerInterceptor(server, cfg)))
+	healthpb.RegisterHealthServer(s, &healthServer{})
+
+	go func() { _ = s.Serve(lis) }()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(client, cfg)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(client, cfg)))
+	assert.NoError(t, err)
+
+	return healthpb.NewHealthClient(conn), func() {
+		_ = conn.Close()
+		s.Stop()
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	server, serverOut := newLogger()
+	server.SetCluster("minikube")
+
+	client, clientOut := newLogger()
+
+	hc, stop := dial(t, server, client, Config{})
+	defer stop()
+
+	parent := log.NewTraceContext()
+
+	ctx := log.ContextWithTrace(log.ContextWithRequestID(context.Background(), "r1"), parent)
+	ctx = metadata.AppendToOutgoingContext(ctx, "user", "johnny")
+
+	var header metadata.MD
+
+	_, err := hc.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"r1"}, header.Get("x-request-id"))
+
+	_, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	lines := jsonLines(serverOut)
+	if assert.Len(t, lines, 4) {
+		for _, f := range lines {
+			assert.Equal(t, "minikube", f["cluster"])
+			assert.Equal(t, "/grpc.health.v1.Health/Check", f["operation"])
+			assert.Equal(t, "bufconn", f[PeerKey])
+		}
+
+		assert.Equal(t, "check", lines[0]["msg"])
+		assert.Equal(t, "johnny", lines[0]["user"])
+		assert.Equal(t, "r1", lines[0][log.RequestIDKey])
+		assert.Equal(t, parent.TraceID, lines[0][log.TraceIDKey])
+		assert.Equal(t, lines[0][log.SpanIDKey], lines[1][log.SpanIDKey])
+
+		assert.Equal(t, "audit api", lines[1]["msg"])
+		assert.Equal(t, "debug", lines[1]["level"])
+		assert.Equal(t, "api", lines[1]["auditType"])
+		assert.Equal(t, "grpc", lines[1]["httpType"])
+		assert.Equal(t, "/grpc.health.v1.Health/Check", lines[1]["endpoint"])
+		assert.Equal(t, "{}", lines[1]["request"])
+		assert.Equal(t, `{"status":"SERVING"}`, lines[1]["response"])
+		assert.Equal(t, float64(0), lines[1]["responseCode"])
+		assert.Equal(t, "OK", lines[1][CodeKey])
+		assert.Equal(t, "r1", lines[1][log.RequestIDKey])
+		assert.NotEmpty(t, lines[1][log.LatencyKey])
+
+		assert.NotContains(t, lines[2], "user")
+		assert.Len(t, lines[2][log.RequestIDKey], 32)
+		assert.NotEqual(t, parent.TraceID, lines[2][log.TraceIDKey])
+		assert.Equal(t, `{"service":"unknown"}`, lines[3]["request"])
+		assert.Equal(t, "", lines[3]["response"])
+		assert.Equal(t, float64(5), lines[3]["responseCode"])
+		assert.Equal(t, "NotFound", lines[3][CodeKey])
+		assert.Contains(t, lines[3]["error"], "unknown service")
+	}
+
+	lines = jsonLines(clientOut)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "audit api", lines[0]["msg"])
+		assert.Equal(t, "/grpc.health.v1.Health/Check", lines[0]["operation"])
+		assert.Equal(t, "bufconn", lines[0][PeerKey])
+		assert.Equal(t, "r1", lines[0][log.RequestIDKey])
+		assert.Equal(t, parent.TraceID, lines[0][log.TraceIDKey])
+		assert.NotEqual(t, parent.SpanID, lines[0][log.SpanIDKey])
+		assert.Equal(t, `{"status":"SERVING"}`, lines[0]["response"])
+
+		assert.Equal(t, "NotFound", lines[1][CodeKey])
+		assert.Len(t, lines[1][log.RequestIDKey], 32)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	server, serverOut := newLogger()
+	client, clientOut := newLogger()
+
+	hc, stop := dial(t, server, client, Config{Messages: &MessageSampling{First: 2, Thereafter: 2}, Level: log.InfoLevel})
+	defer stop()
+
+	stream, err := hc.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if assert.NoError(t, err) {
+		for {
+			if _, err = stream.Recv(); err != nil {
+				break
+			}
+		}
+
+		assert.Equal(t, io.EOF, err)
+	}
+
+	stop()
+
+	// the server receives the request, the client sends it
+	directions := map[string][]map[string]interface{}{"received": jsonLines(serverOut), "sent": jsonLines(clientOut)}
+
+	for request, lines := range directions {
+		var (
+			messages []float64
+			audit    map[string]interface{}
+		)
+
+		for _, f := range lines {
+			assert.Equal(t, "/grpc.health.v1.Health/Watch", f["operation"])
+
+			switch f["msg"] {
+			case "stream message":
+				assert.Equal(t, "trace", f["level"])
+
+				if f[DirectionKey] == request {
+					assert.Equal(t, "{}", f[PayloadKey])
+				} else {
+					assert.Equal(t, `{"status":"SERVING"}`, f[PayloadKey])
+				}
+
+				messages = append(messages, f[CountKey].(float64))
+			case "audit api":
+				audit = f
+			}
+		}
+
+		// 1 request, 5 responses
+		assert.Equal(t, []float64{1, 1, 2, 4}, messages)
+
+		if assert.NotNil(t, audit) {
+			assert.Equal(t, "info", audit["level"])
+			assert.Equal(t, "OK", audit[CodeKey])
+			assert.Equal(t, "", audit["request"])
+			assert.Equal(t, "bufconn", audit[PeerKey])
+		}
+	}
+}
+
+func TestMessageSampling(t *testing.T) {
+	tests := []struct {
+		sampling MessageSampling
+		sampled  []int
+	}{
+		{MessageSampling{}, []int{1, 2, 3, 4, 5, 6}},
+		{MessageSampling{First: 2}, []int{1, 2}},
+		{MessageSampling{Thereafter: 3}, []int{3, 6}},
+		{MessageSampling{First: 1, Thereafter: 2}, []int{1, 3, 5}},
+	}
+
+	for _, tt := range tests {
+		var sampled []int
+
+		for n := 1; n <= 6; n++ {
+			if tt.sampling.sampled(n) {
+				sampled = append(sampled, n)
+			}
+		}
+
+		assert.Equal(t, tt.sampled, sampled)
+	}
+}
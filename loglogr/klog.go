@@ -0,0 +1,14 @@
+package loglogr
+
+import (
+	"github.com/arutselvan15/go-utils/log"
+	"k8s.io/klog/v2"
+)
+
+// RedirectKlog writes the klog entries with the logger (see NewLogger), the returned function restores the
+// klog output
+func RedirectKlog(l log.CommonLog) (restore func()) {
+	klog.SetLogger(NewLogger(l))
+
+	return klog.ClearLogger
+}
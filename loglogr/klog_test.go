@@ -0,0 +1,28 @@
+package loglogr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+)
+
+func TestRedirectKlog(t *testing.T) {
+	l, out := newLogger()
+
+	restore := RedirectKlog(l)
+	defer restore()
+
+	klog.InfoS("synced", "pod", "p1")
+	klog.ErrorS(errors.New("failed"), "sync failed")
+	klog.V(2).Info("not written")
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "synced", fields[0]["msg"])
+		assert.Equal(t, "p1", fields[0]["pod"])
+		assert.Equal(t, "error", fields[1]["level"])
+		assert.Equal(t, "failed", fields[1]["error"])
+	}
+}
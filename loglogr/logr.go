@@ -0,0 +1,101 @@
+// Package loglogr logr sink writing with a log.CommonLog, e.g. for controller-runtime and klog
+package loglogr
+
+import (
+	"fmt"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// logrSink logr sink writing with a logger
+type logrSink struct {
+	log  *log.Log
+	name string
+}
+
+// NewLogger returns a logr logger writing with the logger (see NewSink)
+func NewLogger(l log.CommonLog) logr.Logger {
+	return logr.New(NewSink(l))
+}
+
+// NewSink returns a logr sink writing with a ThreadLogger of the logger: V(0) is info, V(1) debug and above
+// trace, the WithName names joined with dots are the component
+func NewSink(l log.CommonLog) logr.LogSink {
+	return &logrSink{log: l.ThreadLogger()}
+}
+
+// Init does nothing, the caller is not reported
+func (s *logrSink) Init(logr.RuntimeInfo) {}
+
+// Enabled checks the V-level against the logrus logger level
+func (s *logrSink) Enabled(level int) bool {
+	return s.log.GetEntry().Logger.IsLevelEnabled(logrLevel(level))
+}
+
+// Info writes the entry at the level of the V-level
+func (s *logrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.log.WithFields(keyValueFields(keysAndValues)).Log(logrLevel(level), msg)
+}
+
+// Error writes the entry at the error level
+func (s *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	entry := s.log.WithFields(keyValueFields(keysAndValues))
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+
+	entry.Error(msg)
+}
+
+// WithValues returns a sink with the fields added
+func (s *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	child := s.log.ThreadLogger()
+	for key, value := range keyValueFields(keysAndValues) {
+		child.SetField(key, value)
+	}
+
+	return &logrSink{log: child, name: s.name}
+}
+
+// WithName returns a sink with the name appended to the component
+func (s *logrSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+
+	return &logrSink{log: s.log.WithComponent(name), name: name}
+}
+
+// logrLevel maps the V-level of logr
+func logrLevel(level int) logrus.Level {
+	switch {
+	case level <= 0:
+		return logrus.InfoLevel
+	case level == 1:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
+
+// keyValueFields returns the key and value pairs as fields, a key without value is set to "(MISSING)"
+func keyValueFields(keysAndValues []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = "(MISSING)"
+		}
+	}
+
+	return fields
+}
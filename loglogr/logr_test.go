@@ -0,0 +1,92 @@
+package loglogr
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLogger returns a json logger writing to the memory writer
+func newLogger() (log.CommonLog, *log.MemoryWriter) {
+	out := &log.MemoryWriter{}
+
+	l := log.NewLogger().SetFormatterType(log.JSONFormatterType).SetCluster("minikube")
+	l.GetEntry().Logger.SetOutput(out)
+
+	return l, out
+}
+
+// jsonLines returns the fields of the json lines written
+func jsonLines(out *log.MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range out.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestLogr(t *testing.T) {
+	l, out := newLogger()
+	l.SetLevel(log.DebugLevel)
+
+	logger := NewLogger(l).WithName("controller").WithName("pod").WithValues("namespace", "default", 1)
+
+	assert.True(t, logger.Enabled())
+	assert.True(t, logger.V(1).Enabled())
+	assert.False(t, logger.V(2).Enabled())
+
+	logger.Info("reconciled", "name", "p1")
+	logger.V(1).Info("requeued")
+	logger.V(2).Info("not written")
+	logger.Error(errors.New("failed"), "reconcile failed", "odd")
+	logger.Error(nil, "no error")
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 4) {
+		assert.Equal(t, "info", fields[0]["level"])
+		assert.Equal(t, "reconciled", fields[0]["msg"])
+		assert.Equal(t, "minikube", fields[0]["cluster"])
+		assert.Equal(t, "controller.pod", fields[0]["component"])
+		assert.Equal(t, "default", fields[0]["namespace"])
+		assert.Equal(t, "(MISSING)", fields[0]["1"])
+		assert.Equal(t, "p1", fields[0]["name"])
+
+		assert.Equal(t, "debug", fields[1]["level"])
+
+		assert.Equal(t, "error", fields[2]["level"])
+		assert.Equal(t, "failed", fields[2]["error"])
+		assert.Equal(t, "(MISSING)", fields[2]["odd"])
+
+		assert.NotContains(t, fields[3], "error")
+	}
+
+	// the logger is unchanged
+	l.Info("test")
+	assert.NotContains(t, jsonLines(out)[4], "component")
+}
+
+func TestLogrLevel(t *testing.T) {
+	tests := []struct {
+		v     int
+		level logrus.Level
+	}{
+		{-1, logrus.InfoLevel},
+		{0, logrus.InfoLevel},
+		{1, logrus.DebugLevel},
+		{2, logrus.TraceLevel},
+		{10, logrus.TraceLevel},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.level, logrLevel(tt.v))
+	}
+}
@@ -0,0 +1,24 @@
+// Package logotel trace context of the OpenTelemetry spans for the log package
+package logotel
+
+import (
+	"context"
+
+	"github.com/arutselvan15/go-utils/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceFromSpan returns the trace context of the OpenTelemetry span of the context, false when there is none
+func TraceFromSpan(ctx context.Context) (log.TraceContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return log.TraceContext{}, false
+	}
+
+	return log.TraceContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
+
+// Register pulls the trace and span ids of the logs from the OpenTelemetry spans (see log.SetTraceExtractor)
+func Register() {
+	log.SetTraceExtractor(TraceFromSpan)
+}
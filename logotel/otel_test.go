@@ -0,0 +1,36 @@
+package logotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceFromSpan(t *testing.T) {
+	_, ok := TraceFromSpan(context.Background())
+	assert.False(t, ok)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	want := log.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+
+	got, ok := TraceFromSpan(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+
+	// the span comes before the trace stored in the context
+	Register()
+	defer log.SetTraceExtractor(nil)
+
+	got, ok = log.TraceFromContext(log.ContextWithTrace(ctx, log.NewTraceContext()))
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+}
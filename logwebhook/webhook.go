@@ -0,0 +1,321 @@
+// Package logwebhook logging of the kubernetes admission webhooks with a log.CommonLog
+package logwebhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/arutselvan15/go-utils/log"
+	lc "github.com/arutselvan15/go-utils/logconstants"
+	"github.com/arutselvan15/go-utils/redact"
+)
+
+// admission audit field keys
+const (
+	// UIDKey UIDKey
+	UIDKey = "uid"
+	// AllowedKey AllowedKey
+	AllowedKey = "allowed"
+	// PatchKey PatchKey
+	PatchKey = "patch"
+	// CodeKey CodeKey
+	CodeKey = "code"
+	// ReasonKey ReasonKey
+	ReasonKey = "reason"
+	// DryRunKey DryRunKey
+	DryRunKey = "dryRun"
+)
+
+// JSONPatchType patch type of the mutating responses
+const JSONPatchType = "JSONPatch"
+
+// AdmissionReview admission.k8s.io/v1 (or v1beta1) AdmissionReview, only the fields used by the webhooks
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion,omitempty"`
+	Kind       string             `json:"kind,omitempty"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest request of an AdmissionReview
+type AdmissionRequest struct {
+	UID         string               `json:"uid"`
+	Kind        GroupVersionKind     `json:"kind"`
+	Resource    GroupVersionResource `json:"resource"`
+	SubResource string               `json:"subResource,omitempty"`
+	Name        string               `json:"name,omitempty"`
+	Namespace   string               `json:"namespace,omitempty"`
+	Operation   string               `json:"operation"`
+	UserInfo    UserInfo             `json:"userInfo"`
+	Object      json.RawMessage      `json:"object,omitempty"`
+	OldObject   json.RawMessage      `json:"oldObject,omitempty"`
+	DryRun      *bool                `json:"dryRun,omitempty"`
+}
+
+// AdmissionResponse response of an AdmissionReview
+type AdmissionResponse struct {
+	UID       string  `json:"uid"`
+	Allowed   bool    `json:"allowed"`
+	Result    *Status `json:"status,omitempty"`
+	Patch     []byte  `json:"patch,omitempty"`
+	PatchType *string `json:"patchType,omitempty"`
+}
+
+// GroupVersionKind kind of the object
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// GroupVersionResource resource of the object
+type GroupVersionResource struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+// UserInfo user of the request
+type UserInfo struct {
+	Username string   `json:"username"`
+	UID      string   `json:"uid,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// Status result of a denied request
+type Status struct {
+	Code    int32  `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Config configuration of the admission logging
+type Config struct {
+	// Cluster cluster of the webhook, the review has no cluster
+	Cluster string
+	// Component component of the webhook, logconstants.Mutate or logconstants.Validate for instance
+	Component string
+	// Redactor redacts the objects and the patch, redact.Default() when nil
+	Redactor *redact.Redactor
+}
+
+// Admission admission review logged by a webhook
+type Admission struct {
+	Review *AdmissionReview
+
+	log *log.Log
+	cfg Config
+}
+
+// DecodeAdmissionReview decodes the AdmissionReview json, the review must have a request
+func DecodeAdmissionReview(data []byte) (*AdmissionReview, error) {
+	review := &AdmissionReview{}
+	if err := json.Unmarshal(data, review); err != nil {
+		return nil, fmt.Errorf("decode admission review: %v", err)
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("admission review without request")
+	}
+
+	return review, nil
+}
+
+// NewAdmission decodes the AdmissionReview json (see NewAdmissionFromReview)
+func NewAdmission(l log.CommonLog, cfg Config, data []byte) (*Admission, error) {
+	review, err := DecodeAdmissionReview(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAdmissionFromReview(l, cfg, review)
+}
+
+// NewAdmissionFromReview returns the admission of the review with a ThreadLogger of the logger, it has the
+// cluster and component of the config, the resource, operation, object name (namespace/name) and user of the
+// request. The old and new objects are redacted and logged with LogAuditObject.
+func NewAdmissionFromReview(l log.CommonLog, cfg Config, review *AdmissionReview) (*Admission, error) {
+	if review.Request == nil {
+		return nil, fmt.Errorf("admission review without request")
+	}
+
+	if cfg.Redactor == nil {
+		cfg.Redactor = redact.Default()
+	}
+
+	req := review.Request
+
+	oldObject, err := decodeObject(cfg.Redactor, req.OldObject)
+	if err != nil {
+		return nil, fmt.Errorf("decode old object: %v", err)
+	}
+
+	newObject, err := decodeObject(cfg.Redactor, req.Object)
+	if err != nil {
+		return nil, fmt.Errorf("decode object: %v", err)
+	}
+
+	a := &Admission{Review: review, log: l.ThreadLogger(), cfg: cfg}
+
+	a.log.SetResource(resource(req)).SetOperation(operation(req.Operation)).SetObjectName(
+		objectName(req, newObject, oldObject)).SetField(UIDKey, req.UID)
+
+	if cfg.Cluster != "" {
+		a.log.SetCluster(cfg.Cluster)
+	}
+
+	if cfg.Component != "" {
+		a.log.SetComponent(cfg.Component)
+	}
+
+	if req.UserInfo.Username != "" {
+		a.log.SetUser(req.UserInfo.Username)
+	}
+
+	if req.DryRun != nil && *req.DryRun {
+		a.log.SetField(DryRunKey, true)
+	}
+
+	a.log.LogAuditObject(oldObject, newObject)
+
+	return a, nil
+}
+
+// Log returns the logger of the admission
+func (a *Admission) Log() *log.Log {
+	return a.log
+}
+
+// Allow logs the allowed audit event with the redacted patch and returns the response review, the patch is a
+// json patch, none when empty
+func (a *Admission) Allow(patch []byte) *AdmissionReview {
+	resp := &AdmissionResponse{UID: a.Review.Request.UID, Allowed: true}
+
+	l := a.log.With(AllowedKey, true)
+
+	if len(patch) > 0 {
+		patchType := JSONPatchType
+		resp.Patch, resp.PatchType = patch, &patchType
+
+		l = l.With(PatchKey, redactPatch(a.cfg.Redactor, patch))
+	}
+
+	l.LogAuditEvent("admission allowed")
+
+	return a.response(resp)
+}
+
+// Deny logs the denied audit event with the code and reason and returns the response review
+func (a *Admission) Deny(code int32, reason string) *AdmissionReview {
+	a.log.With(AllowedKey, false).With(CodeKey, code).With(ReasonKey, reason).LogAuditEvent("admission denied")
+
+	return a.response(&AdmissionResponse{
+		UID: a.Review.Request.UID, Result: &Status{Code: code, Message: reason},
+	})
+}
+
+// response returns the review of the response, with the api version and kind of the request review
+func (a *Admission) response(resp *AdmissionResponse) *AdmissionReview {
+	return &AdmissionReview{APIVersion: a.Review.APIVersion, Kind: a.Review.Kind, Response: resp}
+}
+
+// decodeObject decodes the redacted object, an empty object when none (create, delete)
+func decodeObject(r *redact.Redactor, data json.RawMessage) (map[string]interface{}, error) {
+	object := map[string]interface{}{}
+
+	if len(data) == 0 || string(data) == "null" {
+		return object, nil
+	}
+
+	if err := json.Unmarshal(r.JSON(data), &object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// redactPatch returns the redacted json patch, the values of the operations on a path ending with a field of
+// the field rules are masked, /spec/password for instance
+func redactPatch(r *redact.Redactor, patch []byte) string {
+	var ops []map[string]interface{}
+
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return r.Text(string(patch))
+	}
+
+	masked := false
+
+	for _, op := range ops {
+		path, _ := op["path"].(string)
+		field := strings.NewReplacer("~1", "/", "~0", "~").Replace(path[strings.LastIndex(path, "/")+1:])
+
+		if _, ok := op["value"]; ok && r.MatchField(field) {
+			op["value"], masked = redact.Mask, true
+		}
+	}
+
+	// the patch is kept as is when no operation is masked
+	if !masked {
+		return r.Text(string(patch))
+	}
+
+	b, err := json.Marshal(ops)
+	if err != nil {
+		return r.Text(string(patch))
+	}
+
+	return r.Text(string(b))
+}
+
+// resource returns the resource with the group and the sub resource, deployments.apps/status for instance
+func resource(req *AdmissionRequest) string {
+	res := req.Resource.Resource
+	if req.Resource.Group != "" {
+		res += "." + req.Resource.Group
+	}
+
+	if req.SubResource != "" {
+		res += "/" + req.SubResource
+	}
+
+	return res
+}
+
+// operation maps the admission operation to the operation
+func operation(op string) string {
+	switch op {
+	case "CREATE":
+		return lc.Create
+	case "UPDATE":
+		return lc.Update
+	case "DELETE":
+		return lc.Delete
+	default:
+		return strings.ToLower(op)
+	}
+}
+
+// objectName returns namespace/name, the name of the objects when the request has none (generateName)
+func objectName(req *AdmissionRequest, objects ...map[string]interface{}) string {
+	name := req.Name
+
+	for _, object := range objects {
+		if name != "" {
+			break
+		}
+
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			name, _ = metadata["name"].(string)
+			if name == "" {
+				name, _ = metadata["generateName"].(string)
+			}
+		}
+	}
+
+	if req.Namespace == "" {
+		return name
+	}
+
+	return req.Namespace + "/" + name
+}
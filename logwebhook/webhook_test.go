@@ -0,0 +1,232 @@
+package logwebhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/log"
+	lc "github.com/arutselvan15/go-utils/logconstants"
+	"github.com/stretchr/testify/assert"
+)
+
+const updateReview = `{
+  "apiVersion": "admission.k8s.io/v1",
+  "kind": "AdmissionReview",
+  "request": {
+    "uid": "705ab4f5",
+    "kind": {"group": "apps", "version": "v1", "kind": "Deployment"},
+    "resource": {"group": "apps", "version": "v1", "resource": "deployments"},
+    "name": "web",
+    "namespace": "shop",
+    "operation": "UPDATE",
+    "userInfo": {"username": "johnny", "groups": ["system:authenticated"]},
+    "object": {"metadata": {"name": "web"}, "spec": {"replicas": 3, "password": "s3cret"}},
+    "oldObject": {"metadata": {"name": "web"}, "spec": {"replicas": 1, "password": "old"}}
+  }
+}`
+
+// newLogger returns a json logger writing to the memory writer
+func newLogger() (log.CommonLog, *log.MemoryWriter) {
+	out := &log.MemoryWriter{}
+
+	l := log.NewLogger().SetFormatterType(log.JSONFormatterType).SetLevel(log.DebugLevel)
+	l.GetEntry().Logger.SetOutput(out)
+
+	return l, out
+}
+
+// jsonLines returns the fields of the json lines written
+func jsonLines(out *log.MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range out.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestDecodeAdmissionReview(t *testing.T) {
+	review, err := DecodeAdmissionReview([]byte(updateReview))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "705ab4f5", review.Request.UID)
+		assert.Equal(t, "Deployment", review.Request.Kind.Kind)
+		assert.Equal(t, []string{"system:authenticated"}, review.Request.UserInfo.Groups)
+	}
+
+	_, err = DecodeAdmissionReview([]byte(`{"kind": "AdmissionReview"}`))
+	assert.EqualError(t, err, "admission review without request")
+
+	_, err = DecodeAdmissionReview([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestAdmission(t *testing.T) {
+	l, out := newLogger()
+
+	a, err := NewAdmission(l, Config{Cluster: "minikube", Component: lc.Mutate}, []byte(updateReview))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	patch := `[{"op":"add","path":"/metadata/labels","value":{"team":"shop"}}]`
+	resp := a.Allow([]byte(patch))
+
+	assert.Equal(t, "admission.k8s.io/v1", resp.APIVersion)
+	assert.Equal(t, "AdmissionReview", resp.Kind)
+	assert.Nil(t, resp.Request)
+	assert.Equal(t, "705ab4f5", resp.Response.UID)
+	assert.True(t, resp.Response.Allowed)
+	assert.Equal(t, JSONPatchType, *resp.Response.PatchType)
+
+	b, err := json.Marshal(resp)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), `"patch":"W3sib3`)
+	}
+
+	a.Log().Info("done")
+
+	got := jsonLines(out)
+	if !assert.Len(t, got, 3) {
+		return
+	}
+
+	for _, f := range got {
+		assert.Equal(t, "minikube", f["cluster"])
+		assert.Equal(t, lc.Mutate, f["component"])
+		assert.Equal(t, "deployments.apps", f["resource"])
+		assert.Equal(t, lc.Update, f["operation"])
+		assert.Equal(t, "shop/web", f["objectName"])
+		assert.Equal(t, "johnny", f["user"])
+		assert.Equal(t, "705ab4f5", f[UIDKey])
+	}
+
+	assert.Equal(t, "object", got[0]["auditType"])
+	assert.Contains(t, got[0]["objectDiff"], "([spec replicas], update, 1, 3)")
+	assert.NotContains(t, got[0]["oldObject"], "old")
+	assert.NotContains(t, got[0]["newObject"], "s3cret")
+
+	assert.Equal(t, "admission allowed", got[1]["msg"])
+	assert.Equal(t, "event", got[1]["auditType"])
+	assert.Equal(t, true, got[1][AllowedKey])
+	assert.Equal(t, patch, got[1][PatchKey])
+
+	assert.Equal(t, "done", got[2]["msg"])
+	assert.NotContains(t, got[2], AllowedKey)
+}
+
+func TestAdmissionPatchRedact(t *testing.T) {
+	tests := []struct {
+		patch string
+		want  string
+	}{
+		{
+			`[{"op":"add","path":"/spec/password","value":"hunter2"}]`,
+			`[{"op":"add","path":"/spec/password","value":"[REDACTED]"}]`,
+		},
+		{
+			`[{"op":"replace","path":"/spec/auth","value":{"user":"u","token":"t1"}},{"op":"remove","path":"/spec/secret"}]`,
+			`[{"op":"replace","path":"/spec/auth","value":{"token":"[REDACTED]","user":"u"}},` +
+				`{"op":"remove","path":"/spec/secret"}]`,
+		},
+		{
+			`[{"op":"add","path":"/metadata/annotations/api~1key","value":"k1"}]`,
+			`[{"op":"add","path":"/metadata/annotations/api~1key","value":"k1"}]`,
+		},
+		{
+			`[ {"path": "/spec/replicas", "op": "replace", "value": 12345678901234567890} ]`,
+			`[ {"path": "/spec/replicas", "op": "replace", "value": 12345678901234567890} ]`,
+		},
+		{
+			`[{"op":"add","path":"/metadata/annotations/api_key","value":"k1"}]`,
+			`[{"op":"add","path":"/metadata/annotations/api_key","value":"[REDACTED]"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		l, out := newLogger()
+
+		a, err := NewAdmission(l, Config{}, []byte(updateReview))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resp := a.Allow([]byte(tt.patch))
+		assert.Equal(t, tt.patch, string(resp.Response.Patch))
+
+		got := jsonLines(out)
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, tt.want, got[1][PatchKey])
+		}
+	}
+}
+
+func TestAdmissionDeny(t *testing.T) {
+	l, out := newLogger()
+
+	data := strings.NewReplacer(`"UPDATE"`, `"CREATE"`, `"name": "web",`, "", `"oldObject"`, `"old"`).Replace(updateReview)
+
+	a, err := NewAdmission(l, Config{Component: lc.Validate}, []byte(data))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp := a.Deny(403, "replicas over quota")
+	assert.False(t, resp.Response.Allowed)
+	assert.Equal(t, &Status{Code: 403, Message: "replicas over quota"}, resp.Response.Result)
+	assert.Nil(t, resp.Response.PatchType)
+
+	got := jsonLines(out)
+	if !assert.Len(t, got, 2) {
+		return
+	}
+
+	assert.Equal(t, lc.Create, got[0]["operation"])
+	assert.Equal(t, "shop/web", got[0]["objectName"])
+	assert.NotContains(t, got[0], "cluster")
+	assert.Contains(t, got[0]["objectDiff"], "([spec], create, <nil>, map[password:[REDACTED] replicas:3])")
+
+	assert.Equal(t, "admission denied", got[1]["msg"])
+	assert.Equal(t, false, got[1][AllowedKey])
+	assert.Equal(t, float64(403), got[1][CodeKey])
+	assert.Equal(t, "replicas over quota", got[1][ReasonKey])
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		op, want string
+	}{
+		{"CREATE", lc.Create},
+		{"UPDATE", lc.Update},
+		{"DELETE", lc.Delete},
+		{"CONNECT", "connect"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, operation(tt.op))
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	tests := []struct {
+		req     AdmissionRequest
+		objects []map[string]interface{}
+		want    string
+	}{
+		{AdmissionRequest{Name: "web", Namespace: "shop"}, nil, "shop/web"},
+		{AdmissionRequest{Name: "node1"}, nil, "node1"},
+		{AdmissionRequest{Namespace: "shop"}, []map[string]interface{}{
+			{"metadata": map[string]interface{}{"generateName": "web-"}},
+		}, "shop/web-"},
+		{AdmissionRequest{}, []map[string]interface{}{{}, {"metadata": map[string]interface{}{"name": "old"}}}, "old"},
+	}
+
+	for _, tt := range tests {
+		req := tt.req
+		assert.Equal(t, tt.want, objectName(&req, tt.objects...))
+	}
+}
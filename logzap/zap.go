@@ -0,0 +1,104 @@
+// Package logzap zap core writing with a log.CommonLog
+package logzap
+
+import (
+	"context"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zapCore zap core writing with a logger
+type zapCore struct {
+	log    log.CommonLog
+	fields logrus.Fields
+}
+
+// NewLogger returns a zap logger writing with the logger (see NewCore)
+func NewLogger(l log.CommonLog, options ...zap.Option) *zap.Logger {
+	return zap.New(NewCore(l), options...)
+}
+
+// NewCore returns a zap core writing with the logger, the zap logger name is the component; dpanic, panic and
+// fatal are written as errors, zap panics or exits after
+func NewCore(l log.CommonLog) zapcore.Core {
+	return &zapCore{log: l, fields: logrus.Fields{}}
+}
+
+// Enabled checks the zap level against the logrus logger level
+func (c *zapCore) Enabled(level zapcore.Level) bool {
+	return c.log.GetEntry().Logger.IsLevelEnabled(zapLevel(level))
+}
+
+// With returns a core with the fields added
+func (c *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &zapCore{log: c.log, fields: zapFields(c.fields, fields)}
+}
+
+// Check adds the core to the entry when its level is enabled
+func (c *zapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Write writes the entry
+func (c *zapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	data := zapFields(c.fields, fields)
+
+	if entry.LoggerName != "" {
+		data[log.ComponentKey] = entry.LoggerName
+	}
+
+	if entry.Stack != "" {
+		data["stacktrace"] = entry.Stack
+	}
+
+	c.log.WithFields(data).WithTime(entry.Time).Log(zapLevel(entry.Level), entry.Message)
+
+	return nil
+}
+
+// Sync writes the buffered entries
+func (c *zapCore) Sync() error {
+	return c.log.Flush(context.Background())
+}
+
+// zapLevel maps the zap level, the levels below debug (logr V-levels through zapr) are trace
+func zapLevel(level zapcore.Level) logrus.Level {
+	switch {
+	case level < zapcore.DebugLevel:
+		return logrus.TraceLevel
+	case level == zapcore.DebugLevel:
+		return logrus.DebugLevel
+	case level == zapcore.InfoLevel:
+		return logrus.InfoLevel
+	case level == zapcore.WarnLevel:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+// zapFields returns a copy of the fields with the zap fields added
+func zapFields(fields logrus.Fields, zfields []zapcore.Field) logrus.Fields {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range zfields {
+		f.AddTo(enc)
+	}
+
+	data := make(logrus.Fields, len(fields)+len(enc.Fields))
+	for k, v := range fields {
+		data[k] = v
+	}
+
+	for k, v := range enc.Fields {
+		data[k] = v
+	}
+
+	return data
+}
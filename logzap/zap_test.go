@@ -0,0 +1,92 @@
+package logzap
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogger returns a json logger writing to the memory writer
+func newLogger() (log.CommonLog, *log.MemoryWriter) {
+	out := &log.MemoryWriter{}
+
+	l := log.NewLogger().SetFormatterType(log.JSONFormatterType).SetCluster("minikube")
+	l.GetEntry().Logger.SetOutput(out)
+
+	return l, out
+}
+
+// jsonLines returns the fields of the json lines written
+func jsonLines(out *log.MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range out.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestZapCore(t *testing.T) {
+	l, out := newLogger()
+
+	logger := NewLogger(l).Named("webhook").With(zap.String("requestId", "r1"))
+
+	assert.True(t, logger.Core().Enabled(zapcore.InfoLevel))
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	logger.Info("admitted", zap.Int("code", 200), zap.Object("user", zapcore.ObjectMarshalerFunc(
+		func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", "u1")
+			return nil
+		})))
+	logger.Debug("not written")
+	logger.Error("denied", zap.Error(errors.New("forbidden")))
+	logger.DPanic("not panicking")
+	assert.NoError(t, logger.Sync())
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, "info", fields[0]["level"])
+		assert.Equal(t, "admitted", fields[0]["msg"])
+		assert.Equal(t, "minikube", fields[0]["cluster"])
+		assert.Equal(t, "webhook", fields[0]["component"])
+		assert.Equal(t, "r1", fields[0]["requestId"])
+		assert.Equal(t, float64(200), fields[0]["code"])
+		assert.Equal(t, map[string]interface{}{"name": "u1"}, fields[0]["user"])
+
+		assert.Equal(t, "error", fields[1]["level"])
+		assert.Equal(t, "forbidden", fields[1]["error"])
+		assert.Equal(t, "r1", fields[1]["requestId"])
+
+		assert.Equal(t, "error", fields[2]["level"])
+	}
+}
+
+func TestZapLevel(t *testing.T) {
+	tests := []struct {
+		zap   zapcore.Level
+		level logrus.Level
+	}{
+		{zapcore.DebugLevel - 2, logrus.TraceLevel},
+		{zapcore.DebugLevel, logrus.DebugLevel},
+		{zapcore.InfoLevel, logrus.InfoLevel},
+		{zapcore.WarnLevel, logrus.WarnLevel},
+		{zapcore.ErrorLevel, logrus.ErrorLevel},
+		{zapcore.PanicLevel, logrus.ErrorLevel},
+		{zapcore.FatalLevel, logrus.ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.level, zapLevel(tt.zap), tt.zap.String())
+	}
+}
@@ -0,0 +1,84 @@
+// Package logzerolog zerolog writer writing with a log.CommonLog
+package logzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// zerologWriter zerolog writer writing the events with a logger
+type zerologWriter struct {
+	log log.CommonLog
+}
+
+// NewLogger returns a zerolog logger writing with the logger (see NewWriter), without timestamp field the
+// entries are timed when written
+func NewLogger(l log.CommonLog) zerolog.Logger {
+	return zerolog.New(NewWriter(l))
+}
+
+// NewWriter returns a zerolog writer decoding the json events into entries of the logger, fatal and panic are
+// written as errors, zerolog exits or panics after
+func NewWriter(l log.CommonLog) zerolog.LevelWriter {
+	return &zerologWriter{log: l}
+}
+
+// Write writes the event at the level of its level field
+func (w *zerologWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel writes the event at the level
+func (w *zerologWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var fields logrus.Fields
+
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	if err := dec.Decode(&fields); err != nil {
+		return 0, err
+	}
+
+	if level == zerolog.NoLevel {
+		if s, ok := fields[zerolog.LevelFieldName].(string); ok {
+			level, _ = zerolog.ParseLevel(s)
+		}
+	}
+
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	ts, _ := fields[zerolog.TimestampFieldName].(string)
+
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+
+	entry := w.log.WithFields(fields)
+	if t, err := time.Parse(zerolog.TimeFieldFormat, ts); err == nil {
+		entry = entry.WithTime(t)
+	}
+
+	entry.Log(zerologLevel(level), msg)
+
+	return len(p), nil
+}
+
+// zerologLevel maps the zerolog level, the events without level are info
+func zerologLevel(level zerolog.Level) logrus.Level {
+	switch level {
+	case zerolog.TraceLevel:
+		return logrus.TraceLevel
+	case zerolog.DebugLevel:
+		return logrus.DebugLevel
+	case zerolog.WarnLevel:
+		return logrus.WarnLevel
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		return logrus.ErrorLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
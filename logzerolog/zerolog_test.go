@@ -0,0 +1,68 @@
+package logzerolog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arutselvan15/go-utils/log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLogger returns a json logger writing to the memory writer
+func newLogger() (log.CommonLog, *log.MemoryWriter) {
+	out := &log.MemoryWriter{}
+
+	l := log.NewLogger().SetFormatterType(log.JSONFormatterType).SetCluster("minikube")
+	l.GetEntry().Logger.SetOutput(out)
+
+	return l, out
+}
+
+// jsonLines returns the fields of the json lines written
+func jsonLines(out *log.MemoryWriter) []map[string]interface{} {
+	var lines []map[string]interface{}
+
+	for _, line := range out.Lines() {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestZerolog(t *testing.T) {
+	l, out := newLogger()
+
+	logger := NewLogger(l).With().Str("requestId", "r1").Logger()
+
+	logger.Info().Int("code", 200).Dict("user", zerolog.Dict().Str("name", "u1")).Msg("admitted")
+	logger.Debug().Msg("not written")
+	logger.Error().Err(errors.New("forbidden")).Time("time", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)).Msg("denied")
+	logger.Log().Msg("no level")
+
+	_, err := NewWriter(l).Write([]byte("not json"))
+	assert.Error(t, err)
+
+	fields := jsonLines(out)
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, "info", fields[0]["level"])
+		assert.Equal(t, "admitted", fields[0]["msg"])
+		assert.Equal(t, "minikube", fields[0]["cluster"])
+		assert.Equal(t, "r1", fields[0]["requestId"])
+		assert.Equal(t, float64(200), fields[0]["code"])
+		assert.Equal(t, map[string]interface{}{"name": "u1"}, fields[0]["user"])
+		assert.NotContains(t, fields[0], "message")
+
+		assert.Equal(t, "error", fields[1]["level"])
+		assert.Equal(t, "forbidden", fields[1]["error"])
+		assert.Equal(t, "2020-01-02T03:04:05.000000000Z", fields[1]["time"])
+
+		assert.Equal(t, "info", fields[2]["level"])
+		assert.Equal(t, "no level", fields[2]["msg"])
+	}
+}
@@ -0,0 +1,139 @@
+package redact
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment step of a json path: a name, an index, a wildcard (* or [*]) or a recursive name (..name)
+type pathSegment struct {
+	name      string
+	index     int
+	wildcard  bool
+	recursive bool
+}
+
+// parsePath parses the json path subset $.name, $['name'], $[0], $[*], $.*, $..name
+func parsePath(path string) ([]pathSegment, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid redact path %q: %s", path, reason)
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, invalid("must start with $")
+	}
+
+	var segments []pathSegment
+
+	for p := path[1:]; p != ""; {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			name, rest := splitName(p[2:])
+			if name == "" {
+				return nil, invalid("empty name after ..")
+			}
+
+			segments = append(segments, pathSegment{name: name, recursive: true})
+			p = rest
+		case strings.HasPrefix(p, "."):
+			name, rest := splitName(p[1:])
+			if name == "" {
+				return nil, invalid("empty name after .")
+			}
+
+			segments = append(segments, pathSegment{name: name, wildcard: name == "*"})
+			p = rest
+		case strings.HasPrefix(p, "["):
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+
+			inner := p[1:end]
+			p = p[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{name: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, invalid("bad index " + inner)
+				}
+
+				segments = append(segments, pathSegment{name: inner, index: index})
+			}
+		default:
+			return nil, invalid("unexpected " + p)
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid("no field")
+	}
+
+	return segments, nil
+}
+
+func splitName(p string) (string, string) {
+	end := strings.IndexAny(p, ".[")
+	if end < 0 {
+		return p, ""
+	}
+
+	return p[:end], p[end:]
+}
+
+// maskPath masks the values of the document matching the path, masked is set when a value is masked
+func maskPath(doc interface{}, path []pathSegment, masked *bool) interface{} {
+	if len(path) == 0 {
+		*masked = true
+		return Mask
+	}
+
+	seg, rest := path[0], path[1:]
+
+	if seg.recursive {
+		return maskRecursive(doc, seg.name, rest, masked)
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if seg.wildcard || key == seg.name {
+				v[key] = maskPath(value, rest, masked)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if seg.wildcard || (seg.name != "" && i == seg.index && strconv.Itoa(i) == seg.name) {
+				v[i] = maskPath(value, rest, masked)
+			}
+		}
+	}
+
+	return doc
+}
+
+// maskRecursive applies the rest of the path to the name at any depth
+func maskRecursive(doc interface{}, name string, rest []pathSegment, masked *bool) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == name {
+				v[key] = maskPath(value, rest, masked)
+			} else {
+				v[key] = maskRecursive(value, name, rest, masked)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = maskRecursive(value, name, rest, masked)
+		}
+	}
+
+	return doc
+}
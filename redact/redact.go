@@ -0,0 +1,453 @@
+// Package redact lib
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Tag struct tag of the fields to redact: `redact:"true"`
+	Tag = "redact"
+	// Mask replacement of the redacted values
+	Mask = "[REDACTED]"
+)
+
+// DefaultFields field names redacted by default, compared case insensitive without - and _
+var DefaultFields = []string{
+	"password", "passwd", "secret", "clientSecret", "token", "accessToken", "refreshToken", "apiKey",
+	"authorization", "cookie",
+}
+
+// DefaultPatterns regex rules applied by default
+var DefaultPatterns = []Pattern{
+	{Name: "bearer", Regexp: regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), Replace: "${1}" + Mask},
+	{Name: "jwt", Regexp: regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`), Replace: Mask},
+	{Name: "email", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Replace: Mask},
+	{Name: "creditCard", Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Replace: Mask, Valid: luhn},
+}
+
+// Pattern regex rule
+type Pattern struct {
+	// Name name of the rule
+	Name string
+	// Regexp matches the text to redact
+	Regexp *regexp.Regexp
+	// Replace replacement of the matches, can refer to the groups ($1)
+	Replace string
+	// Valid redacts only the matches it returns true for, all the matches when nil
+	Valid func(match string) bool
+}
+
+// Config rules of a Redactor
+type Config struct {
+	// Fields field names, of the log fields and inside the json values
+	Fields []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Patterns regular expressions of the text to redact in the string values
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+	// Paths json paths ($.spec.password, $.items[*].token, $..secret) redacted inside the json values
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	// NoDefaults does not add the DefaultFields and DefaultPatterns
+	NoDefaults bool `json:"noDefaults,omitempty" yaml:"noDefaults,omitempty"`
+}
+
+// Redactor redacts field names, regex matches and json paths
+type Redactor struct {
+	fields   map[string]bool
+	patterns []Pattern
+	paths    [][]pathSegment
+}
+
+// New creates a redactor with the rules of the config
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{fields: map[string]bool{}}
+
+	fields := cfg.Fields
+	if !cfg.NoDefaults {
+		fields = append(append([]string{}, DefaultFields...), fields...)
+		r.patterns = append(r.patterns, DefaultPatterns...)
+	}
+
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("invalid redact field: empty name")
+		}
+
+		r.fields[normalize(field)] = true
+	}
+
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %v", pattern, err)
+		}
+
+		r.patterns = append(r.patterns, Pattern{Name: pattern, Regexp: re, Replace: Mask})
+	}
+
+	for _, path := range cfg.Paths {
+		segments, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r.paths = append(r.paths, segments)
+	}
+
+	return r, nil
+}
+
+// Default creates a redactor with the DefaultFields and DefaultPatterns
+func Default() *Redactor {
+	r, _ := New(Config{})
+	return r
+}
+
+// AddPattern adds a regex rule
+func (r *Redactor) AddPattern(p Pattern) *Redactor {
+	r.patterns = append(r.patterns, p)
+	return r
+}
+
+// MatchField checks the field name against the field rules
+func (r *Redactor) MatchField(name string) bool {
+	return r.fields[normalize(name)]
+}
+
+// Fields returns the fields with the values redacted, the fields are copied only when a value changes
+func (r *Redactor) Fields(fields map[string]interface{}) map[string]interface{} {
+	var redacted map[string]interface{}
+
+	for key, value := range fields {
+		v := r.Value(key, value)
+		if reflect.DeepEqual(v, value) {
+			continue
+		}
+
+		if redacted == nil {
+			redacted = make(map[string]interface{}, len(fields))
+			for k, v := range fields {
+				redacted[k] = v
+			}
+		}
+
+		redacted[key] = v
+	}
+
+	if redacted == nil {
+		return fields
+	}
+
+	return redacted
+}
+
+// Value redacts the value of the field: masked when the name matches a field rule, json strings get
+// the field, path and regex rules, the other strings and the errors the regex rules
+func (r *Redactor) Value(name string, value interface{}) interface{} {
+	if r.MatchField(name) {
+		return Mask
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.Text(v)
+	case error:
+		if s := r.Text(v.Error()); s != v.Error() {
+			return s
+		}
+	}
+
+	return value
+}
+
+// Text redacts the json text as JSON, the other text with the regex rules, the text is unchanged when nothing
+// is masked
+func (r *Redactor) Text(s string) string {
+	if t := strings.TrimSpace(s); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		if b, masked, ok := r.json([]byte(s)); ok {
+			if !masked {
+				return s
+			}
+
+			return string(b)
+		}
+	}
+
+	return r.String(s)
+}
+
+// String applies the regex rules
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		if p.Valid == nil {
+			s = p.Regexp.ReplaceAllString(s, p.Replace)
+			continue
+		}
+
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if !p.Valid(match) {
+				return match
+			}
+
+			return p.Regexp.ReplaceAllString(match, p.Replace)
+		})
+	}
+
+	return s
+}
+
+// JSON redacts the json document with the field, path and regex rules, invalid json gets the regex rules, the
+// document is unchanged when nothing is masked
+func (r *Redactor) JSON(data []byte) []byte {
+	if b, masked, ok := r.json(data); ok {
+		if !masked {
+			return data
+		}
+
+		return b
+	}
+
+	return []byte(r.String(string(data)))
+}
+
+// json returns the redacted document, masked is false when no rule matched
+func (r *Redactor) json(data []byte) (b []byte, masked bool, ok bool) {
+	doc, err := unmarshal(data)
+	if err != nil {
+		return nil, false, false
+	}
+
+	for _, path := range r.paths {
+		doc = maskPath(doc, path, &masked)
+	}
+
+	doc = r.walk(doc, &masked)
+	if !masked {
+		return nil, false, true
+	}
+
+	var buf bytes.Buffer
+
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+
+	if err := e.Encode(doc); err != nil {
+		return nil, false, false
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true, true
+}
+
+// walk applies the field and regex rules to the document, masked is set when a value is masked
+func (r *Redactor) walk(doc interface{}, masked *bool) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if r.MatchField(key) {
+				v[key], *masked = Mask, true
+			} else {
+				v[key] = r.walk(value, masked)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.walk(value, masked)
+		}
+	case string:
+		if s := r.String(v); s != v {
+			*masked = true
+			return s
+		}
+	}
+
+	return doc
+}
+
+// Marshal marshals the value to json with the fields tagged `redact:"true"` masked
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v)
+	if !hasTagged(t, map[reflect.Type]bool{}) {
+		return data, nil
+	}
+
+	doc, err := unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(maskTagged(doc, t))
+}
+
+// Tagged checks the field has the `redact:"true"` tag
+func Tagged(field reflect.StructField) bool {
+	return field.Tag.Get(Tag) == "true"
+}
+
+// HasTagged checks the type has fields with the `redact:"true"` tag, directly or in its elements and fields
+func HasTagged(t reflect.Type) bool {
+	return hasTagged(t, map[reflect.Type]bool{})
+}
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// hasTagged checks the type has tagged fields, the types marshalling themselves are not walked
+func hasTagged(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == nil || seen[t] || t.Implements(jsonMarshaler) {
+		return false
+	}
+
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasTagged(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if Tagged(t.Field(i)) || hasTagged(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// maskTagged masks the tagged fields of the unmarshalled document of the type
+func maskTagged(doc interface{}, t reflect.Type) interface{} {
+	if t == nil || t.Implements(jsonMarshaler) {
+		return doc
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return maskTagged(doc, t.Elem())
+	case reflect.Slice, reflect.Array:
+		if items, ok := doc.([]interface{}); ok {
+			for i, item := range items {
+				items[i] = maskTagged(item, t.Elem())
+			}
+		}
+	case reflect.Map:
+		if m, ok := doc.(map[string]interface{}); ok {
+			for key, value := range m {
+				m[key] = maskTagged(value, t.Elem())
+			}
+		}
+	case reflect.Struct:
+		if m, ok := doc.(map[string]interface{}); ok {
+			maskStruct(m, t)
+		}
+	}
+
+	return doc
+}
+
+func maskStruct(m map[string]interface{}, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		// fields of the embedded structs are promoted
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				maskStruct(m, ft)
+				continue
+			}
+
+			name = ft.Name()
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		value, ok := m[name]
+		if !ok {
+			continue
+		}
+
+		if Tagged(field) {
+			m[name] = Mask
+		} else {
+			m[name] = maskTagged(value, field.Type)
+		}
+	}
+}
+
+// jsonName returns the json tag name of the field, false when the field is not marshalled
+func jsonName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	return strings.Split(tag, ",")[0], true
+}
+
+func unmarshal(data []byte) (interface{}, error) {
+	var doc interface{}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if d.More() {
+		return nil, fmt.Errorf("invalid json: data after the document")
+	}
+
+	return doc, nil
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+}
+
+// luhn checks the digits of the card number
+func luhn(number string) bool {
+	sum, n := 0, 0
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		n++
+	}
+
+	return n >= 13 && sum%10 == 0
+}